@@ -0,0 +1,191 @@
+package sreq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CircuitClosed means requests are allowed.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen means requests fail fast without being sent.
+	CircuitOpen
+
+	// CircuitHalfOpen means a probe request is allowed to check whether the host recovers.
+	CircuitHalfOpen
+)
+
+type (
+	// CircuitState represents the state of a circuit.
+	CircuitState int
+
+	// CircuitBreaker stops sending requests to a host for a while after consecutive failures,
+	// it's concurrent safe and can be shared by clients.
+	// Attach it to a client with Client.Use(breaker.Middleware).
+	CircuitBreaker struct {
+		// FailureThreshold specifies the consecutive failures that open the circuit of a host.
+		FailureThreshold int
+
+		// OpenTimeout specifies how long a circuit stays open before allowing a probe request.
+		OpenTimeout time.Duration
+
+		// IsFailure reports whether a round trip is failed.
+		// If nil, network errors and 5xx status codes are treated as failures.
+		// Round trips canceled by the caller, i.e. context.Canceled, are neither failures nor successes,
+		// they are ignored by the circuit breaker. Timeouts are failures.
+		IsFailure func(httpResp *http.Response, err error) bool
+
+		circuits map[string]*circuit
+		mux      sync.Mutex
+	}
+
+	circuit struct {
+		state    CircuitState
+		failures int
+		openedAt time.Time
+		probing  bool
+	}
+
+	// CircuitOpenError is returned when the circuit of the requested host is open.
+	CircuitOpenError struct {
+		// Host specifies the requested host.
+		Host string
+	}
+)
+
+// String implements fmt.Stringer interface.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// Error implements error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("sreq: circuit breaker is open for %q", e.Host)
+}
+
+// NewCircuitBreaker returns a circuit breaker that opens the circuit of a host after failureThreshold
+// consecutive failures, and allows a probe request after openTimeout.
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		OpenTimeout:      openTimeout,
+	}
+}
+
+// State returns the circuit state of the given host, which is in the form of "host:port" if a port is present.
+func (cb *CircuitBreaker) State(host string) CircuitState {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	c := cb.circuit(host)
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= cb.OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// circuit returns the circuit of host, the caller must hold cb.mux.
+func (cb *CircuitBreaker) circuit(host string) *circuit {
+	host = strings.ToLower(host)
+	if cb.circuits == nil {
+		cb.circuits = make(map[string]*circuit)
+	}
+
+	c, ok := cb.circuits[host]
+	if !ok {
+		c = new(circuit)
+		cb.circuits[host] = c
+	}
+	return c
+}
+
+func (cb *CircuitBreaker) allow(host string) error {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	c := cb.circuit(host)
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < cb.OpenTimeout {
+			return &CircuitOpenError{Host: host}
+		}
+		c.state = CircuitHalfOpen
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			return &CircuitOpenError{Host: host}
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+func (cb *CircuitBreaker) record(host string, failed bool) {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	c := cb.circuit(host)
+	c.probing = false
+	if !failed {
+		c.state = CircuitClosed
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= cb.FailureThreshold {
+		c.state = CircuitOpen
+		c.openedAt = time.Now()
+	}
+}
+
+// release gives up the probe of host, if any, without changing the state of its circuit.
+func (cb *CircuitBreaker) release(host string) {
+	cb.mux.Lock()
+	cb.circuit(host).probing = false
+	cb.mux.Unlock()
+}
+
+func (cb *CircuitBreaker) isFailure(httpResp *http.Response, err error) bool {
+	if cb.IsFailure != nil {
+		return cb.IsFailure(httpResp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return httpResp.StatusCode >= 500
+}
+
+// Middleware fails the HTTP requests fast if the circuit of the requested host is open.
+func (cb *CircuitBreaker) Middleware(next Doer) Doer {
+	return DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+		host := httpReq.URL.Host
+		if err := cb.allow(host); err != nil {
+			return nil, err
+		}
+
+		httpResp, err := next.Do(httpReq)
+		if err != nil && errors.Is(err, context.Canceled) {
+			cb.release(host)
+			return httpResp, err
+		}
+
+		cb.record(host, cb.isFailure(httpResp, err))
+		return httpResp, err
+	})
+}
@@ -0,0 +1,155 @@
+package sreq_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		healthy  int32
+		attempts int32
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	breaker := sreq.NewCircuitBreaker(2, 100*time.Millisecond)
+	req := sreq.New(nil)
+	req.Use(breaker.Middleware)
+
+	for i := 0; i < 2; i++ {
+		req.Get(ts.URL).Raw()
+	}
+	if state := breaker.State(u.Host); state != sreq.CircuitOpen {
+		t.Fatalf("CircuitBreaker got state: %s, want: %s", state, sreq.CircuitOpen)
+	}
+
+	_, err := req.
+		Get(ts.URL,
+			sreq.WithRetry(&sreq.RetryPolicy{
+				MaxAttempts: 3,
+			}),
+		).
+		Resolve()
+	var circuitErr *sreq.CircuitOpenError
+	if !errors.As(err, &circuitErr) || circuitErr.Host != u.Host {
+		t.Errorf("CircuitBreaker got error: %v, want: *sreq.CircuitOpenError", err)
+	}
+	if attempts != 2 {
+		t.Errorf("CircuitBreaker got attempts: %d, want: %d", attempts, 2)
+	}
+
+	// A failed probe opens the circuit again.
+	time.Sleep(150 * time.Millisecond)
+	if state := breaker.State(u.Host); state != sreq.CircuitHalfOpen {
+		t.Fatalf("CircuitBreaker got state: %s, want: %s", state, sreq.CircuitHalfOpen)
+	}
+	req.Get(ts.URL).Raw()
+	if state := breaker.State(u.Host); state != sreq.CircuitOpen {
+		t.Fatalf("CircuitBreaker got state: %s, want: %s", state, sreq.CircuitOpen)
+	}
+
+	// A successful probe closes the circuit.
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(150 * time.Millisecond)
+	if _, err = req.Get(ts.URL).EnsureStatusOk().Raw(); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(u.Host); state != sreq.CircuitClosed {
+		t.Errorf("CircuitBreaker got state: %s, want: %s", state, sreq.CircuitClosed)
+	}
+}
+
+func TestCircuitBreaker_Canceled(t *testing.T) {
+	var healthy int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	breaker := sreq.NewCircuitBreaker(1, 50*time.Millisecond)
+	req := sreq.New(nil)
+	req.Use(breaker.Middleware)
+
+	req.Get(ts.URL).Raw()
+	if state := breaker.State(u.Host); state != sreq.CircuitOpen {
+		t.Fatalf("CircuitBreaker got state: %s, want: %s", state, sreq.CircuitOpen)
+	}
+
+	// A canceled probe neither closes nor opens the circuit.
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(100 * time.Millisecond)
+	if _, err := req.Get(ts.URL, sreq.WithContext(canceledContext())).Raw(); err == nil {
+		t.Fatal("CircuitBreaker canceled probe succeeded")
+	}
+	if state := breaker.State(u.Host); state != sreq.CircuitHalfOpen {
+		t.Errorf("CircuitBreaker got state: %s, want: %s", state, sreq.CircuitHalfOpen)
+	}
+
+	if _, err := req.Get(ts.URL).EnsureStatusOk().Raw(); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(u.Host); state != sreq.CircuitClosed {
+		t.Errorf("CircuitBreaker got state: %s, want: %s", state, sreq.CircuitClosed)
+	}
+}
+
+func TestCircuitBreaker_Timeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	for _, test := range []struct {
+		client *http.Client
+		opts   []sreq.RequestOption
+	}{
+		{
+			client: &http.Client{Timeout: 20 * time.Millisecond},
+		},
+		{
+			opts: []sreq.RequestOption{sreq.WithTimeout(20 * time.Millisecond)},
+		},
+	} {
+		breaker := sreq.NewCircuitBreaker(2, time.Minute)
+		req := sreq.New(test.client)
+		req.Use(breaker.Middleware)
+		for i := 0; i < 2; i++ {
+			if _, err := req.Get(ts.URL, test.opts...).Raw(); err == nil {
+				t.Fatal("CircuitBreaker timeout unchecked")
+			}
+		}
+		if state := breaker.State(u.Host); state != sreq.CircuitOpen {
+			t.Errorf("CircuitBreaker after timeouts got state: %s, want: %s", state, sreq.CircuitOpen)
+		}
+	}
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,432 @@
+package sreq
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// fromCacheHeader marks the HTTP responses served by Cache.
+	fromCacheHeader = "X-From-Cache"
+
+	defaultMaxCacheEntrySize = 1 << 20
+)
+
+type (
+	// CacheStorage stores the entries of Cache, it must be concurrent safe.
+	CacheStorage interface {
+		// Get returns the value of the given key.
+		Get(key string) ([]byte, bool)
+
+		// Set sets the value of the given key.
+		Set(key string, value []byte)
+
+		// Delete deletes the value of the given key.
+		Delete(key string)
+	}
+
+	// Cache is a private HTTP cache for GET requests, which honors Cache-Control, Expires and Vary,
+	// and revalidates stale responses with If-None-Match or If-Modified-Since.
+	// Attach it to a client with Client.Use(cache.Middleware).
+	Cache struct {
+		// MaxEntrySize specifies the max body size of a response to be stored, at most MaxEntrySize+1
+		// bytes of the body are read ahead to tell. Larger responses are passed through without being
+		// stored, so that their bodies are still streamed. NewCache sets it to 1 MiB.
+		MaxEntrySize int64
+
+		storage CacheStorage
+	}
+
+	cacheEntry struct {
+		StoredAt time.Time         `json:"storedAt"`
+		Vary     map[string]string `json:"vary,omitempty"`
+		Response []byte            `json:"response"`
+	}
+
+	cacheControl map[string]string
+
+	// MemoryCacheStorage is an in-memory CacheStorage which evicts the least recently used entries.
+	MemoryCacheStorage struct {
+		capacity int
+		ll       *list.List
+		items    map[string]*list.Element
+		mux      sync.Mutex
+	}
+
+	memoryCacheItem struct {
+		key   string
+		value []byte
+	}
+
+	// DiskCacheStorage is a CacheStorage which stores entries as files in a directory.
+	DiskCacheStorage struct {
+		dir string
+	}
+)
+
+// NewCache returns an HTTP cache which stores entries in storage.
+func NewCache(storage CacheStorage) *Cache {
+	return &Cache{
+		MaxEntrySize: defaultMaxCacheEntrySize,
+		storage:      storage,
+	}
+}
+
+// FromCache reports whether the HTTP response of r is served by Cache.
+func (r *Response) FromCache() bool {
+	return r.Err == nil && r.R != nil && r.R.Header.Get(fromCacheHeader) != ""
+}
+
+// Middleware serves the HTTP requests sent by next from the cache if possible.
+func (c *Cache) Middleware(next Doer) Doer {
+	return DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+		key := httpReq.URL.String()
+		if httpReq.Method != MethodGet {
+			httpResp, err := next.Do(httpReq)
+			if err == nil && httpReq.Method != MethodHead && httpResp.StatusCode < 400 {
+				// Unsafe methods invalidate the cached response.
+				c.storage.Delete(key)
+			}
+			return httpResp, err
+		}
+
+		reqCC := parseCacheControl(httpReq.Header)
+		if _, ok := reqCC["no-store"]; ok || httpReq.Header.Get("Range") != "" {
+			return next.Do(httpReq)
+		}
+
+		entry := c.load(key, httpReq)
+		if entry == nil {
+			httpResp, err := next.Do(httpReq)
+			if err == nil {
+				c.store(key, httpReq, httpResp)
+			}
+			return httpResp, err
+		}
+
+		cached, err := entry.response(httpReq)
+		if err != nil {
+			c.storage.Delete(key)
+			return next.Do(httpReq)
+		}
+		if entry.fresh(cached, reqCC) {
+			cached.Header.Set(fromCacheHeader, "1")
+			return cached, nil
+		}
+
+		condReq := httpReq
+		etag, lastModified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			condReq = httpReq.Clone(httpReq.Context())
+			if etag != "" && condReq.Header.Get("If-None-Match") == "" {
+				condReq.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" && condReq.Header.Get("If-Modified-Since") == "" {
+				condReq.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+
+		httpResp, err := next.Do(condReq)
+		if err != nil {
+			return nil, err
+		}
+		if httpResp.StatusCode != http.StatusNotModified || condReq == httpReq {
+			c.store(key, httpReq, httpResp)
+			return httpResp, nil
+		}
+
+		drainBody(&Response{R: httpResp})
+		for k, v := range httpResp.Header {
+			if k != "Content-Length" {
+				cached.Header[k] = v
+			}
+		}
+		c.store(key, httpReq, cached)
+		cached.Header.Set(fromCacheHeader, "1")
+		return cached, nil
+	})
+}
+
+func (c *Cache) load(key string, httpReq *http.Request) *cacheEntry {
+	b, ok := c.storage.Get(key)
+	if !ok {
+		return nil
+	}
+
+	entry := new(cacheEntry)
+	if err := json.Unmarshal(b, entry); err != nil {
+		c.storage.Delete(key)
+		return nil
+	}
+	for name, value := range entry.Vary {
+		if strings.Join(httpReq.Header[http.CanonicalHeaderKey(name)], ", ") != value {
+			return nil
+		}
+	}
+	return entry
+}
+
+// store stores the HTTP response if it's cacheable, the response body is preserved.
+func (c *Cache) store(key string, httpReq *http.Request, httpResp *http.Response) {
+	maxEntrySize := c.maxEntrySize()
+	if !cacheable(httpResp) || httpResp.ContentLength > maxEntrySize {
+		return
+	}
+
+	// The size is unknown for chunked or transparently decompressed responses.
+	body := httpResp.Body
+	b, err := ioutil.ReadAll(io.LimitReader(body, maxEntrySize+1))
+	if err != nil || int64(len(b)) > maxEntrySize {
+		httpResp.Body = &struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: io.MultiReader(bytes.NewReader(b), body),
+			Closer: body,
+		}
+		return
+	}
+	body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	httpResp.ContentLength = int64(len(b))
+
+	entry := &cacheEntry{
+		StoredAt: time.Now(),
+	}
+	for _, v := range httpResp.Header["Vary"] {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if entry.Vary == nil {
+				entry.Vary = make(map[string]string)
+			}
+			entry.Vary[name] = strings.Join(httpReq.Header[http.CanonicalHeaderKey(name)], ", ")
+		}
+	}
+
+	b, err = httputil.DumpResponse(httpResp, true)
+	if err != nil {
+		return
+	}
+	entry.Response = b
+	if b, err = json.Marshal(entry); err == nil {
+		c.storage.Set(key, b)
+	}
+}
+
+func (c *Cache) maxEntrySize() int64 {
+	if c.MaxEntrySize <= 0 {
+		return defaultMaxCacheEntrySize
+	}
+	return c.MaxEntrySize
+}
+
+func cacheable(httpResp *http.Response) bool {
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusPermanentRedirect, http.StatusNotFound, http.StatusGone:
+	default:
+		return false
+	}
+
+	cc := parseCacheControl(httpResp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	for _, v := range httpResp.Header["Vary"] {
+		if strings.TrimSpace(v) == "*" {
+			return false
+		}
+	}
+
+	return freshnessLifetime(httpResp, cc) > 0 ||
+		httpResp.Header.Get("ETag") != "" || httpResp.Header.Get("Last-Modified") != ""
+}
+
+func (e *cacheEntry) response(httpReq *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(e.Response)), httpReq)
+}
+
+// fresh reports whether the cached response can be served without revalidation.
+func (e *cacheEntry) fresh(cached *http.Response, reqCC cacheControl) bool {
+	if _, ok := reqCC["no-cache"]; ok {
+		return false
+	}
+	cc := parseCacheControl(cached.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return false
+	}
+
+	age := time.Since(e.StoredAt)
+	if seconds, err := strconv.Atoi(cached.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	if v, ok := reqCC["max-age"]; ok {
+		if seconds, err := strconv.Atoi(v); err == nil && age > time.Duration(seconds)*time.Second {
+			return false
+		}
+	}
+	return age < freshnessLifetime(cached, cc)
+}
+
+func freshnessLifetime(httpResp *http.Response, cc cacheControl) time.Duration {
+	if v, ok := cc["max-age"]; ok {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if v := httpResp.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		date, err := http.ParseTime(httpResp.Header.Get("Date"))
+		if err != nil {
+			date = time.Now()
+		}
+		return expires.Sub(date)
+	}
+	return 0
+}
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, v := range header["Cache-Control"] {
+		for _, directive := range strings.Split(v, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+
+			var value string
+			if i := strings.Index(directive, "="); i >= 0 {
+				directive, value = directive[:i], strings.Trim(directive[i+1:], `"`)
+			}
+			cc[strings.ToLower(directive)] = value
+		}
+	}
+	return cc
+}
+
+// NewMemoryCacheStorage returns an in-memory cache storage which holds capacity entries at most.
+func NewMemoryCacheStorage(capacity int) *MemoryCacheStorage {
+	return &MemoryCacheStorage{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStorage interface.
+func (s *MemoryCacheStorage) Get(key string) ([]byte, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	e, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.ll.MoveToFront(e)
+	return e.Value.(*memoryCacheItem).value, true
+}
+
+// Set implements CacheStorage interface.
+func (s *MemoryCacheStorage) Set(key string, value []byte) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		e.Value.(*memoryCacheItem).value = value
+		s.ll.MoveToFront(e)
+		return
+	}
+
+	s.items[key] = s.ll.PushFront(&memoryCacheItem{
+		key:   key,
+		value: value,
+	})
+	for s.capacity > 0 && s.ll.Len() > s.capacity {
+		e := s.ll.Back()
+		s.ll.Remove(e)
+		delete(s.items, e.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete implements CacheStorage interface.
+func (s *MemoryCacheStorage) Delete(key string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		s.ll.Remove(e)
+		delete(s.items, key)
+	}
+}
+
+// NewDiskCacheStorage returns a cache storage which stores entries in dir, dir is created if not exists.
+func NewDiskCacheStorage(dir string) (*DiskCacheStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &DiskCacheStorage{
+		dir: dir,
+	}, nil
+}
+
+func (s *DiskCacheStorage) filename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get implements CacheStorage interface.
+func (s *DiskCacheStorage) Get(key string) ([]byte, bool) {
+	b, err := ioutil.ReadFile(s.filename(key))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// Set implements CacheStorage interface.
+func (s *DiskCacheStorage) Set(key string, value []byte) {
+	file, err := ioutil.TempFile(s.dir, "tmp")
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write(value)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), s.filename(key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+}
+
+// Delete implements CacheStorage interface.
+func (s *DiskCacheStorage) Delete(key string) {
+	os.Remove(s.filename(key))
+}
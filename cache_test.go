@@ -0,0 +1,203 @@
+package sreq_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestCache(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			fmt.Fprint(w, r.Header.Get("Accept-Language"))
+			return
+		}
+		fmt.Fprintf(w, "hit %d", n)
+	}))
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewCache(sreq.NewMemoryCacheStorage(100)).Middleware)
+
+	get := func(path string, opts ...sreq.RequestOption) (string, bool) {
+		resp := req.Get(ts.URL+path, opts...).EnsureStatusOk()
+		data, err := resp.Text()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data, resp.FromCache()
+	}
+
+	get("/max-age")
+	if data, fromCache := get("/max-age"); data != "hit 1" || !fromCache {
+		t.Errorf("Cache got: %q, from cache: %t", data, fromCache)
+	}
+	if data, fromCache := get("/max-age", sreq.WithHeaders(sreq.Headers{
+		"Cache-Control": "no-cache",
+	})); data != "hit 2" || fromCache {
+		t.Errorf("Request no-cache got: %q, from cache: %t", data, fromCache)
+	}
+
+	// Unsafe methods invalidate the cached response.
+	req.Post(ts.URL + "/max-age").Raw()
+	if _, fromCache := get("/max-age"); fromCache {
+		t.Error("Cache not invalidated by POST")
+	}
+
+	get("/no-store")
+	if _, fromCache := get("/no-store"); fromCache {
+		t.Error("Response no-store stored")
+	}
+
+	en := sreq.WithHeaders(sreq.Headers{"Accept-Language": "en"})
+	zh := sreq.WithHeaders(sreq.Headers{"Accept-Language": "zh"})
+	get("/vary", en)
+	if data, fromCache := get("/vary", zh); data != "zh" || fromCache {
+		t.Errorf("Vary got: %q, from cache: %t", data, fromCache)
+	}
+	if data, fromCache := get("/vary", zh); data != "zh" || !fromCache {
+		t.Errorf("Vary got: %q, from cache: %t", data, fromCache)
+	}
+}
+
+func TestCache_Revalidate(t *testing.T) {
+	var hits, notModified int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `{"version":1}`)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	storage, err := sreq.NewDiskCacheStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := sreq.New(nil)
+	req.Use(sreq.NewCache(storage).Middleware)
+
+	for i := 0; i < 3; i++ {
+		resp := req.Get(ts.URL).EnsureStatusOk()
+		data, err := resp.Text()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data != `{"version":1}` {
+			t.Errorf("Revalidated response got: %q", data)
+		}
+		if fromCache := resp.FromCache(); fromCache != (i > 0) {
+			t.Errorf("Response_FromCache got: %t, want: %t", fromCache, i > 0)
+		}
+	}
+	if hits != 3 || notModified != 2 {
+		t.Errorf("Cache revalidation got hits: %d, not modified: %d", hits, notModified)
+	}
+}
+
+func TestCache_MaxEntrySize(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		switch r.URL.Path {
+		case "/chunked":
+			fmt.Fprint(w, "hello ")
+			w.(http.Flusher).Flush()
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			fmt.Fprint(zw, "hello world")
+			zw.Close()
+			return
+		}
+		fmt.Fprint(w, "hello world")
+	}))
+	defer ts.Close()
+
+	cache := sreq.NewCache(sreq.NewMemoryCacheStorage(100))
+	req := sreq.New(nil)
+	req.Use(cache.Middleware)
+
+	tests := []struct {
+		maxEntrySize int64
+		path         string
+		want         string
+		stored       bool
+	}{
+		{10, "/", "hello world", false},
+		{10, "/chunked", "hello hello world", false},
+		{10, "/gzip", "hello world", false},
+		{11, "/", "hello world", true},
+		{17, "/chunked", "hello hello world", true},
+		{11, "/gzip", "hello world", true},
+	}
+	for _, test := range tests {
+		cache.MaxEntrySize = test.maxEntrySize
+		atomic.StoreInt32(&hits, 0)
+		for i := 0; i < 3; i++ {
+			resp := req.Get(ts.URL+test.path, sreq.WithQuery(sreq.Params{
+				"max": strconv.FormatInt(test.maxEntrySize, 10),
+			})).EnsureStatusOk()
+			data, err := resp.Text()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data != test.want {
+				t.Errorf("Cache %s got body: %q, want: %q", test.path, data, test.want)
+			}
+		}
+		if want := map[bool]int32{false: 3, true: 1}[test.stored]; hits != want {
+			t.Errorf("Cache %s with MaxEntrySize %d got hits: %d, want: %d",
+				test.path, test.maxEntrySize, hits, want)
+		}
+	}
+}
+
+func TestMemoryCacheStorage(t *testing.T) {
+	s := sreq.NewMemoryCacheStorage(2)
+	s.Set("k1", []byte("v1"))
+	s.Set("k2", []byte("v2"))
+	s.Get("k1")
+	s.Set("k3", []byte("v3"))
+
+	if _, ok := s.Get("k2"); ok {
+		t.Error("Least recently used entry not evicted")
+	}
+	if v, ok := s.Get("k1"); !ok || string(v) != "v1" {
+		t.Error("Recently used entry evicted")
+	}
+
+	s.Delete("k1")
+	if _, ok := s.Get("k1"); ok {
+		t.Error("MemoryCacheStorage_Delete test failed")
+	}
+}
@@ -2,6 +2,7 @@ package sreq
 
 import (
 	"errors"
+	"io"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
@@ -23,6 +24,15 @@ type (
 		// RequestOptions specifies request options that sreq uses for per HTTP request by default.
 		RequestOptions []RequestOption
 
+		// RetryPolicy specifies the retry policy that sreq uses for per HTTP request by default.
+		RetryPolicy *RetryPolicy
+
+		// Middlewares specifies middlewares that sreq runs around per HTTP request.
+		Middlewares []Middleware
+
+		// BaseURL specifies the URL that relative request URLs resolve against.
+		BaseURL string
+
 		mux sync.RWMutex
 	}
 )
@@ -158,9 +168,98 @@ func Send(httpReq *http.Request) *Response {
 
 // Send sends an HTTP request and returns its response.
 func (c *Client) Send(httpReq *http.Request) *Response {
-	httpResp, err := c.C.Do(httpReq)
+	httpReq, err := applyDeferred(httpReq)
+	if err != nil {
+		return &Response{
+			Err: err,
+		}
+	}
+	httpReq = applyTimeout(httpReq)
+
+	var resp *Response
+	if policy := c.retryPolicy(httpReq); policy != nil {
+		resp = c.sendWithRetry(httpReq, policy)
+	} else {
+		resp = c.do(httpReq)
+	}
+
+	releaseTimeout(httpReq, resp)
+	return resp
+}
+
+func (c *Client) do(httpReq *http.Request) *Response {
+	httpResp, err := c.doer().Do(httpReq)
+	if err == nil {
+		trackDownloadProgress(httpReq, httpResp)
+	}
 	return &Response{
 		R:   httpResp,
 		Err: err,
 	}
 }
+
+// hookBody calls fn once the body is read to EOF or closed.
+type hookBody struct {
+	io.ReadCloser
+	once sync.Once
+	fn   func()
+}
+
+func newHookBody(body io.ReadCloser, fn func()) *hookBody {
+	return &hookBody{
+		ReadCloser: body,
+		fn:         fn,
+	}
+}
+
+func (b *hookBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.fn)
+	}
+	return n, err
+}
+
+func (b *hookBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.fn)
+	return err
+}
+
+// configureTransport applies fn to a copy of the transport of c and then replaces it.
+func (c *Client) configureTransport(fn func(t *http.Transport)) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	// Unwrap the dumpers, possibly nested by Dumper.Transport, and wrap the new transport with them.
+	transport := c.C.Transport
+	var dumpers []*Dumper
+	for {
+		dt, ok := transport.(*dumpTransport)
+		if !ok {
+			break
+		}
+		dumpers = append(dumpers, dt.dumper)
+		transport = dt.next
+	}
+	wrap := func(rt http.RoundTripper) http.RoundTripper {
+		for i := len(dumpers) - 1; i >= 0; i-- {
+			rt = dumpers[i].Transport(rt)
+		}
+		return rt
+	}
+
+	var t *http.Transport
+	switch transport := transport.(type) {
+	case nil:
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		t = transport.Clone()
+	default:
+		return errors.New("sreq: transport is not an *http.Transport")
+	}
+
+	fn(t)
+	c.C.Transport = wrap(t)
+	return nil
+}
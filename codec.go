@@ -0,0 +1,145 @@
+package sreq
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type (
+	// Codec encodes and decodes payloads of a specific content type, like JSON, XML, etc.
+	// Register a codec with RegisterCodec so that Response.Decode can pick it.
+	Codec interface {
+		// ContentType returns the media type of the encoded data.
+		ContentType() string
+
+		// Marshal returns the encoding of v.
+		Marshal(v interface{}) ([]byte, error)
+
+		// Unmarshal parses the encoded data and stores the result in the value pointed to by v.
+		Unmarshal(data []byte, v interface{}) error
+	}
+
+	jsonCodec struct{}
+
+	xmlCodec struct{}
+)
+
+var (
+	// JSONCodec is the codec of JSON.
+	JSONCodec Codec = jsonCodec{}
+
+	// XMLCodec is the codec of XML.
+	XMLCodec Codec = xmlCodec{}
+
+	codecs = map[string]Codec{
+		"application/json": JSONCodec,
+		"text/json":        JSONCodec,
+		"application/xml":  XMLCodec,
+		"text/xml":         XMLCodec,
+	}
+	codecsMux sync.RWMutex
+)
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (xmlCodec) ContentType() string {
+	return "application/xml"
+}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// RegisterCodec registers a codec for the given content type, like "application/x-yaml",
+// it replaces the existing one if any.
+func RegisterCodec(contentType string, codec Codec) {
+	codecsMux.Lock()
+	codecs[strings.ToLower(contentType)] = codec
+	codecsMux.Unlock()
+}
+
+// LookupCodec returns the codec registered for the given content type.
+// Structured syntax suffixes like "+json" and "+xml" are also recognized.
+func LookupCodec(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	codecsMux.RLock()
+	defer codecsMux.RUnlock()
+
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		codec, ok := codecs["application/"+mediaType[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// WithBody sets payload of the HTTP request, which is encoded by codec.
+func WithBody(codec Codec, v interface{}) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		b, err := codec.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return WithRaw(b, codec.ContentType())(hr)
+	}
+}
+
+// WithXML sets xml payload of the HTTP request.
+func WithXML(v interface{}) RequestOption {
+	return WithBody(XMLCodec, v)
+}
+
+// XML decodes the HTTP response body of r and unmarshals its XML-encoded data into v.
+func (r *Response) XML(v interface{}) error {
+	return r.decode(XMLCodec, v)
+}
+
+// Decode decodes the HTTP response body of r and unmarshals its data into v,
+// using the codec registered for the response's Content-Type.
+func (r *Response) Decode(v interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+
+	contentType := r.R.Header.Get("Content-Type")
+	codec, ok := LookupCodec(contentType)
+	if !ok {
+		return fmt.Errorf("sreq: no codec for content type %q", contentType)
+	}
+	return r.decode(codec, v)
+}
+
+func (r *Response) decode(codec Codec, v interface{}) error {
+	b, err := r.Raw()
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(b, v)
+}
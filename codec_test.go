@@ -0,0 +1,128 @@
+package sreq_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+type csvCodec struct{}
+
+func (csvCodec) ContentType() string {
+	return "text/csv"
+}
+
+func (csvCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.Join(v.([]string), ",")), nil
+}
+
+func (csvCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]string)) = strings.Split(string(data), ",")
+	return nil
+}
+
+func TestWithXML(t *testing.T) {
+	type message struct {
+		XMLName xml.Name `xml:"message"`
+		Msg     string   `xml:"msg"`
+		Num     int      `xml:"num"`
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/xml" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	resp := sreq.
+		Post(ts.URL,
+			sreq.WithXML(&message{
+				Msg: "hello world",
+				Num: 2019,
+			}),
+		).
+		EnsureStatusOk()
+
+	got := new(message)
+	if err := resp.XML(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Msg != "hello world" || got.Num != 2019 {
+		t.Errorf("Response_XML got: %+v", got)
+	}
+
+	got = new(message)
+	if err := resp.Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Msg != "hello world" || got.Num != 2019 {
+		t.Errorf("Response_Decode got: %+v", got)
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json")
+			fmt.Fprint(w, `{"title":"not found"}`)
+		case "/unknown":
+			w.Header().Set("Content-Type", "application/octet-stream")
+		default:
+			w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+			b, _ := ioutil.ReadAll(r.Body)
+			w.Write(b)
+		}
+	}))
+	defer ts.Close()
+
+	if _, ok := sreq.LookupCodec("text/csv"); ok {
+		t.Fatal("Unregistered codec found")
+	}
+	sreq.RegisterCodec("text/csv", csvCodec{})
+
+	var values []string
+	err := sreq.
+		Post(ts.URL,
+			sreq.WithBody(csvCodec{}, []string{"a", "b", "c"}),
+		).
+		EnsureStatusOk().
+		Decode(&values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(values, "|") != "a|b|c" {
+		t.Errorf("Response_Decode with custom codec got: %v", values)
+	}
+
+	problem := make(map[string]string)
+	err = sreq.
+		Get(ts.URL + "/problem").
+		EnsureStatusOk().
+		Decode(&problem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problem["title"] != "not found" {
+		t.Errorf("Response_Decode with suffix got: %v", problem)
+	}
+
+	err = sreq.
+		Get(ts.URL + "/unknown").
+		EnsureStatusOk().
+		Decode(&problem)
+	if err == nil {
+		t.Error("Unknown content type unchecked")
+	}
+}
@@ -0,0 +1,80 @@
+package sreq
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// EncodingGzip represents gzip content encoding.
+	EncodingGzip = "gzip"
+
+	// EncodingDeflate represents deflate content encoding, i.e. the zlib format.
+	EncodingDeflate = "deflate"
+)
+
+// WithCompression compresses payload of the HTTP request with the given content encoding,
+// payloads smaller than minSize bytes are sent as is.
+// The payload is compressed after all the other options are applied, so it can be used
+// as a default request option.
+func WithCompression(encoding string, minSize int64) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		if encoding != EncodingGzip && encoding != EncodingDeflate {
+			return nil, fmt.Errorf("sreq: unsupported content encoding: %q", encoding)
+		}
+		return deferOption(hr, stageCompress, func(hr *http.Request) (*http.Request, error) {
+			return compressBody(hr, encoding, minSize)
+		}), nil
+	}
+}
+
+func compressBody(hr *http.Request, encoding string, minSize int64) (*http.Request, error) {
+	if hr.Body == nil || hr.Body == http.NoBody || hr.Header.Get("Content-Encoding") != "" {
+		return hr, nil
+	}
+	if hr.ContentLength > 0 && hr.ContentLength < minSize {
+		return hr, nil
+	}
+
+	raw, err := bodyBytes(hr)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) < minSize {
+		setBody(hr, raw)
+		return hr, nil
+	}
+
+	b, err := compress(encoding, raw)
+	if err != nil {
+		return nil, err
+	}
+	setBody(hr, b)
+	hr.Header.Set("Content-Encoding", encoding)
+	return hr, nil
+}
+
+func compress(encoding string, raw []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	default:
+		w = zlib.NewWriter(&buf)
+	}
+
+	if _, err := w.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
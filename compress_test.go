@@ -0,0 +1,176 @@
+package sreq_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestWithCompression(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			body io.Reader = r.Body
+			err  error
+		)
+		switch r.Header.Get("Content-Encoding") {
+		case sreq.EncodingGzip:
+			body, err = gzip.NewReader(r.Body)
+		case sreq.EncodingDeflate:
+			body, err = zlib.NewReader(r.Body)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("X-Content-Encoding", r.Header.Get("Content-Encoding"))
+		io.Copy(w, body)
+	}))
+	defer ts.Close()
+
+	text := strings.Repeat("hello world", 100)
+	tests := []struct {
+		encoding string
+		text     string
+		want     string
+	}{
+		{
+			encoding: sreq.EncodingGzip,
+			text:     text,
+			want:     sreq.EncodingGzip,
+		},
+		{
+			encoding: sreq.EncodingDeflate,
+			text:     text,
+			want:     sreq.EncodingDeflate,
+		},
+		{
+			encoding: sreq.EncodingGzip,
+			text:     "hello world",
+			want:     "",
+		},
+	}
+
+	for _, test := range tests {
+		resp := sreq.
+			Post(ts.URL,
+				sreq.WithText(test.text),
+				sreq.WithCompression(test.encoding, 1024),
+			).
+			EnsureStatusOk()
+		data, err := resp.Text()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if data != test.text {
+			t.Errorf("WithCompression got body: %q", data)
+		}
+		if got := resp.R.Header.Get("X-Content-Encoding"); got != test.want {
+			t.Errorf("WithCompression got encoding: %q, want: %q", got, test.want)
+		}
+	}
+
+	_, err := sreq.
+		Post(ts.URL,
+			sreq.WithText(text),
+			sreq.WithCompression("br", 0),
+		).
+		Resolve()
+	if err == nil {
+		t.Error("Unsupported content encoding unchecked")
+	}
+}
+
+func TestWithCompression_Replayable(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(zr)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	_, err := sreq.
+		Post(ts.URL,
+			sreq.WithJSON(sreq.JSON{
+				"msg": "hello world",
+			}, false),
+			sreq.WithCompression(sreq.EncodingGzip, 0),
+			sreq.WithRetry(&sreq.RetryPolicy{
+				MaxAttempts:        2,
+				RetryNonIdempotent: true,
+			}),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], "hello world") {
+		t.Errorf("WithCompression replay got: %q", bodies)
+	}
+}
+
+func TestWithCompression_Default(t *testing.T) {
+	key := []byte("secret")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, key)
+		mac.Write(b)
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		io.Copy(w, zr)
+	}))
+	defer ts.Close()
+
+	// The compressed payload is signed, whatever the order of the options is.
+	req := sreq.New(nil)
+	req.SetDefaultRequestOpts(
+		sreq.WithHMAC(&sreq.HMACSigner{
+			Key: key,
+			Canonicalize: func(hr *http.Request, body []byte) string {
+				return string(body)
+			},
+		}),
+		sreq.WithCompression(sreq.EncodingGzip, 0),
+	)
+	data, err := req.
+		Post(ts.URL,
+			sreq.WithText("hello world"),
+		).
+		EnsureStatusOk().
+		Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "hello world" {
+		t.Errorf("Default WithCompression got body: %q", data)
+	}
+}
@@ -0,0 +1,570 @@
+package sreq
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// CookieJSON represents the JSON format of the cookie files.
+	CookieJSON CookieFormat = iota
+
+	// CookieNetscape represents the Netscape cookies.txt format used by curl, wget, etc.
+	CookieNetscape
+)
+
+const netscapeHTTPOnlyPrefix = "#HttpOnly_"
+
+var (
+	errCookieIllegalDomain   = errors.New("sreq: illegal cookie domain attribute")
+	errCookieMalformedDomain = errors.New("sreq: malformed cookie domain attribute")
+)
+
+type (
+	// CookieFormat is the format of the cookie files, CookieJSON or CookieNetscape.
+	CookieFormat int
+
+	// Cookie represents a cookie stored in CookieJar.
+	Cookie struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+
+		// Domain is the domain the cookie belongs to, without the leading dot.
+		Domain string `json:"domain"`
+		Path   string `json:"path"`
+
+		// Expires is the expiry of the cookie, zero for session cookies.
+		Expires time.Time `json:"expires"`
+
+		Secure   bool          `json:"secure,omitempty"`
+		HttpOnly bool          `json:"httpOnly,omitempty"`
+		SameSite http.SameSite `json:"sameSite,omitempty"`
+
+		// HostOnly reports whether the cookie is sent to the exact host of Domain only,
+		// otherwise it's sent to the subdomains too.
+		HostOnly bool `json:"hostOnly,omitempty"`
+
+		Creation   time.Time `json:"creation"`
+		LastAccess time.Time `json:"lastAccess"`
+
+		seqNum uint64
+	}
+
+	// CookieJar is an http.CookieJar implementation like net/http/cookiejar with the public suffix list,
+	// in addition, its cookies can be listed, set, deleted, saved to and loaded from files.
+	// It's concurrent safe.
+	CookieJar struct {
+		// entries is keyed by the eTLD+1 of the cookie domains, then by "domain;path;name".
+		entries map[string]map[string]*Cookie
+		nextSeq uint64
+		mux     sync.Mutex
+	}
+)
+
+// NewCookieJar returns an empty cookie jar, use it with New(&http.Client{Jar: jar}).
+func NewCookieJar() *CookieJar {
+	return &CookieJar{
+		entries: make(map[string]map[string]*Cookie),
+	}
+}
+
+func (c *Cookie) id() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+func (c *Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+func (c *Cookie) domainMatch(host string) bool {
+	if c.Domain == host {
+		return true
+	}
+	return !c.HostOnly && hasDotSuffix(host, c.Domain)
+}
+
+func (c *Cookie) pathMatch(requestPath string) bool {
+	if requestPath == c.Path {
+		return true
+	}
+	if strings.HasPrefix(requestPath, c.Path) {
+		return c.Path[len(c.Path)-1] == '/' || requestPath[len(c.Path)] == '/'
+	}
+	return false
+}
+
+// httpCookie returns the cookie to send in an HTTP request.
+func (c *Cookie) httpCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:  c.Name,
+		Value: c.Value,
+	}
+}
+
+// Cookies implements http.CookieJar interface.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host, err := canonicalCookieHost(u.Host)
+	if err != nil {
+		return nil
+	}
+	secure := u.Scheme == "https"
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	key := cookieJarKey(host)
+	submap := j.entries[key]
+	now := time.Now()
+	var selected []*Cookie
+	for id, c := range submap {
+		if c.expired(now) {
+			delete(submap, id)
+			continue
+		}
+		if (!c.Secure || secure) && c.domainMatch(host) && c.pathMatch(path) {
+			c.LastAccess = now
+			selected = append(selected, c)
+		}
+	}
+	if len(submap) == 0 {
+		delete(j.entries, key)
+	}
+
+	// The longer paths are listed first, then the earlier created ones, see RFC 6265 section 5.4.
+	sort.Slice(selected, func(i, k int) bool {
+		a, b := selected[i], selected[k]
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		if !a.Creation.Equal(b.Creation) {
+			return a.Creation.Before(b.Creation)
+		}
+		return a.seqNum < b.seqNum
+	})
+
+	cookies := make([]*http.Cookie, len(selected))
+	for i, c := range selected {
+		cookies[i] = c.httpCookie()
+	}
+	return cookies
+}
+
+// SetCookies implements http.CookieJar interface.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if len(cookies) == 0 || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	host, err := canonicalCookieHost(u.Host)
+	if err != nil {
+		return
+	}
+	defaultPath := defaultCookiePath(u.Path)
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	now := time.Now()
+	for _, hc := range cookies {
+		domain, hostOnly, err := cookieDomain(host, hc.Domain)
+		if err != nil {
+			continue
+		}
+
+		c := &Cookie{
+			Name:     hc.Name,
+			Value:    hc.Value,
+			Domain:   domain,
+			Path:     hc.Path,
+			Secure:   hc.Secure,
+			HttpOnly: hc.HttpOnly,
+			SameSite: hc.SameSite,
+			HostOnly: hostOnly,
+		}
+		if c.Path == "" || c.Path[0] != '/' {
+			c.Path = defaultPath
+		}
+
+		switch {
+		case hc.MaxAge < 0:
+			j.delete(c.id())
+			continue
+		case hc.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(hc.MaxAge) * time.Second)
+		case !hc.Expires.IsZero():
+			if !hc.Expires.After(now) {
+				j.delete(c.id())
+				continue
+			}
+			c.Expires = hc.Expires
+		}
+
+		j.set(c, now)
+	}
+}
+
+// List returns the cookies which would be sent to the given domain regardless of the path,
+// or all the cookies if domain is empty. The expired cookies are removed.
+func (j *CookieJar) List(domain string) []*Cookie {
+	host, err := canonicalCookieHost(domain)
+	if err != nil {
+		return nil
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	now := time.Now()
+	var cookies []*Cookie
+	for key, submap := range j.entries {
+		for id, c := range submap {
+			if c.expired(now) {
+				delete(submap, id)
+				continue
+			}
+			if host == "" || c.domainMatch(host) {
+				copied := *c
+				cookies = append(cookies, &copied)
+			}
+		}
+		if len(submap) == 0 {
+			delete(j.entries, key)
+		}
+	}
+
+	sort.Slice(cookies, func(i, k int) bool {
+		a, b := cookies[i], cookies[k]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Name < b.Name
+	})
+	return cookies
+}
+
+// Set stores the cookie for any domain, it replaces the cookie with the same domain, path and name.
+// The domain must not be a public suffix unless the cookie is host-only, and the path is "/" if empty.
+func (j *CookieJar) Set(cookie *Cookie) error {
+	if cookie.Name == "" {
+		return errors.New("sreq: cookie name must be specified")
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+	if domain == "" || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return errCookieMalformedDomain
+	}
+	if !cookie.HostOnly && net.ParseIP(domain) == nil {
+		if ps, _ := publicsuffix.PublicSuffix(domain); ps == domain {
+			return errCookieIllegalDomain
+		}
+	}
+
+	c := *cookie
+	c.Domain = domain
+	if c.Path == "" {
+		c.Path = "/"
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	j.set(&c, time.Now())
+	return nil
+}
+
+// Delete deletes the cookie with the given domain, path and name, and reports whether it exists.
+func (j *CookieJar) Delete(domain string, path string, name string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if path == "" {
+		path = "/"
+	}
+
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	return j.delete(domain + ";" + path + ";" + name)
+}
+
+func (j *CookieJar) set(c *Cookie, now time.Time) {
+	key := cookieJarKey(c.Domain)
+	submap := j.entries[key]
+	if submap == nil {
+		submap = make(map[string]*Cookie)
+		j.entries[key] = submap
+	}
+
+	if old, ok := submap[c.id()]; ok {
+		c.Creation = old.Creation
+		c.seqNum = old.seqNum
+	} else {
+		if c.Creation.IsZero() {
+			c.Creation = now
+		}
+		c.seqNum = j.nextSeq
+		j.nextSeq++
+	}
+	if c.LastAccess.IsZero() {
+		c.LastAccess = now
+	}
+	submap[c.id()] = c
+}
+
+func (j *CookieJar) delete(id string) bool {
+	domain := id[:strings.Index(id, ";")]
+	key := cookieJarKey(domain)
+	submap := j.entries[key]
+	if _, ok := submap[id]; !ok {
+		return false
+	}
+
+	delete(submap, id)
+	if len(submap) == 0 {
+		delete(j.entries, key)
+	}
+	return true
+}
+
+// Save writes all the unexpired cookies to w in the given format.
+func (j *CookieJar) Save(w io.Writer, format CookieFormat) error {
+	cookies := j.List("")
+	switch format {
+	case CookieJSON:
+		if cookies == nil {
+			cookies = []*Cookie{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cookies)
+	case CookieNetscape:
+		return writeNetscapeCookies(w, cookies)
+	default:
+		return fmt.Errorf("sreq: unsupported cookie format: %d", format)
+	}
+}
+
+// Load reads cookies from r in the given format and merges them into the jar,
+// the expired cookies are skipped.
+func (j *CookieJar) Load(r io.Reader, format CookieFormat) error {
+	var (
+		cookies []*Cookie
+		err     error
+	)
+	switch format {
+	case CookieJSON:
+		err = json.NewDecoder(r).Decode(&cookies)
+	case CookieNetscape:
+		cookies, err = readNetscapeCookies(r)
+	default:
+		err = fmt.Errorf("sreq: unsupported cookie format: %d", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, c := range cookies {
+		if c == nil || c.expired(now) {
+			continue
+		}
+		if err = j.Set(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveFile writes all the unexpired cookies to the named file in the given format.
+// The file is replaced atomically and only readable by the owner since cookies may carry credentials.
+func (j *CookieJar) SaveFile(filename string, format CookieFormat) error {
+	file, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	err = j.Save(file, format)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// LoadFile reads cookies from the named file in the given format and merges them into the jar.
+func (j *CookieJar) LoadFile(filename string, format CookieFormat) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return j.Load(file, format)
+}
+
+func writeNetscapeCookies(w io.Writer, cookies []*Cookie) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("# Netscape HTTP Cookie File\n\n")
+	for _, c := range cookies {
+		domain := c.Domain
+		if !c.HostOnly {
+			domain = "." + domain
+		}
+		if c.HttpOnly {
+			domain = netscapeHTTPOnlyPrefix + domain
+		}
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!c.HostOnly), c.Path, netscapeBool(c.Secure), expires, c.Name, c.Value)
+	}
+	return bw.Flush()
+}
+
+func readNetscapeCookies(r io.Reader) ([]*Cookie, error) {
+	var cookies []*Cookie
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, netscapeHTTPOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, netscapeHTTPOnlyPrefix)
+		} else if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("sreq: malformed cookie file at line %d", lineNum)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sreq: malformed cookie expiry at line %d", lineNum)
+		}
+
+		c := &Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   strings.TrimPrefix(fields[0], "."),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+		}
+		if expires > 0 {
+			c.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, scanner.Err()
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// canonicalCookieHost strips the port and the trailing dot from host and lowers its case.
+func canonicalCookieHost(host string) (string, error) {
+	if strings.LastIndex(host, ":") > strings.LastIndex(host, "]") {
+		var err error
+		host, _, err = net.SplitHostPort(host)
+		if err != nil {
+			return "", err
+		}
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	return strings.ToLower(host), nil
+}
+
+// cookieJarKey returns the eTLD+1 of host, or host itself if it's an IP address or a public suffix.
+func cookieJarKey(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	key, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return key
+}
+
+// cookieDomain returns the domain of a cookie received from host with the given domain attribute,
+// and whether it's host-only.
+func cookieDomain(host string, domain string) (string, bool, error) {
+	if domain == "" {
+		return host, true, nil
+	}
+	if net.ParseIP(host) != nil {
+		if strings.Trim(domain, "[]") != host {
+			return "", false, errCookieIllegalDomain
+		}
+		return host, true, nil
+	}
+
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if domain == "" || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", false, errCookieMalformedDomain
+	}
+
+	// A cookie for a public suffix is accepted as a host-only cookie of the public suffix itself.
+	if ps, _ := publicsuffix.PublicSuffix(domain); ps == domain {
+		if host == domain {
+			return host, true, nil
+		}
+		return "", false, errCookieIllegalDomain
+	}
+
+	if host != domain && !hasDotSuffix(host, domain) {
+		return "", false, errCookieIllegalDomain
+	}
+	return domain, false, nil
+}
+
+// defaultCookiePath returns the directory part of the request path, see RFC 6265 section 5.1.4.
+func defaultCookiePath(path string) string {
+	if path == "" || path[0] != '/' {
+		return "/"
+	}
+
+	i := strings.LastIndex(path, "/")
+	if i == 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+func hasDotSuffix(s string, suffix string) bool {
+	return len(s) > len(suffix) && s[len(s)-len(suffix)-1] == '.' && s[len(s)-len(suffix):] == suffix
+}
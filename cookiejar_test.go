@@ -0,0 +1,202 @@
+package sreq_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+func mustParseURL(t *testing.T, rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func cookieString(cookies []*http.Cookie) string {
+	s := make([]string, len(cookies))
+	for i, c := range cookies {
+		s[i] = c.Name + "=" + c.Value
+	}
+	return strings.Join(s, "; ")
+}
+
+func TestCookieJar(t *testing.T) {
+	jar := sreq.NewCookieJar()
+	jar.SetCookies(mustParseURL(t, "https://www.example.com/account/login"), []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "secure", Value: "3", Path: "/account", Secure: true},
+		{Name: "suffix", Value: "4", Domain: "com"},
+		{Name: "other", Value: "5", Domain: "example.org"},
+		{Name: "expired", Value: "6", Expires: time.Now().Add(-time.Hour)},
+	})
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.example.com/account/login", "host=1; secure=3; domain=2"},
+		{"http://www.example.com/account/", "host=1; domain=2"},
+		{"https://www.example.com/", "domain=2"},
+		{"https://api.example.com/account", "domain=2"},
+		{"https://example.org/", ""},
+		{"ftp://www.example.com/account", ""},
+	}
+	for _, test := range tests {
+		if got := cookieString(jar.Cookies(mustParseURL(t, test.url))); got != test.want {
+			t.Errorf("CookieJar cookies for %s got: %q, want: %q", test.url, got, test.want)
+		}
+	}
+
+	jar.SetCookies(mustParseURL(t, "https://www.example.com/"), []*http.Cookie{
+		{Name: "domain", Domain: "example.com", MaxAge: -1},
+	})
+	if got := cookieString(jar.Cookies(mustParseURL(t, "https://api.example.com/"))); got != "" {
+		t.Errorf("CookieJar deleted cookie got: %q", got)
+	}
+}
+
+func TestCookieJar_SetDelete(t *testing.T) {
+	jar := sreq.NewCookieJar()
+	if err := jar.Set(&sreq.Cookie{Name: "uid", Value: "10086", Domain: ".example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jar.Set(&sreq.Cookie{Name: "lang", Value: "en", Domain: "www.example.com", HostOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jar.Set(&sreq.Cookie{Name: "bad", Domain: "co.uk"}); err == nil {
+		t.Error("CookieJar public suffix domain unchecked")
+	}
+
+	if got := len(jar.List("")); got != 2 {
+		t.Errorf("CookieJar list all got: %d cookies, want: 2", got)
+	}
+	cookies := jar.List("api.example.com")
+	if len(cookies) != 1 || cookies[0].Name != "uid" || cookies[0].Path != "/" || cookies[0].HostOnly {
+		t.Errorf("CookieJar list got: %+v", cookies)
+	}
+
+	if !jar.Delete("example.com", "/", "uid") {
+		t.Error("CookieJar delete existing cookie failed")
+	}
+	if jar.Delete("example.com", "/", "uid") {
+		t.Error("CookieJar delete nonexistent cookie succeeded")
+	}
+	if got := cookieString(jar.Cookies(mustParseURL(t, "http://www.example.com/"))); got != "lang=en" {
+		t.Errorf("CookieJar cookies got: %q, want: %q", got, "lang=en")
+	}
+}
+
+func TestCookieJar_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	jar := sreq.NewCookieJar()
+	jar.SetCookies(mustParseURL(t, "https://www.example.com/"), []*http.Cookie{
+		{Name: "session", Value: "abc", HttpOnly: true},
+		{Name: "uid", Value: "10086", Domain: "example.com", Path: "/api", Expires: expires, Secure: true},
+	})
+
+	for _, format := range []sreq.CookieFormat{sreq.CookieJSON, sreq.CookieNetscape} {
+		filename := filepath.Join(dir, "cookies")
+		if err = jar.SaveFile(filename, format); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded := sreq.NewCookieJar()
+		if err = loaded.LoadFile(filename, format); err != nil {
+			t.Fatal(err)
+		}
+		cookies := loaded.List("")
+		if len(cookies) != 2 {
+			t.Fatalf("CookieJar loaded %d cookies, want: 2", len(cookies))
+		}
+
+		uid, session := cookies[0], cookies[1]
+		if uid.Name != "uid" || uid.Domain != "example.com" || uid.Path != "/api" || uid.HostOnly ||
+			!uid.Secure || !uid.Expires.Equal(expires) {
+			t.Errorf("CookieJar loaded cookie got: %+v", uid)
+		}
+		if session.Name != "session" || session.Domain != "www.example.com" || !session.HostOnly ||
+			!session.HttpOnly || !session.Expires.IsZero() {
+			t.Errorf("CookieJar loaded session cookie got: %+v", session)
+		}
+	}
+}
+
+func TestCookieJar_Netscape(t *testing.T) {
+	past := time.Now().Add(-time.Hour).Unix()
+	data := "# Netscape HTTP Cookie File\n" +
+		"# https://curl.haxx.se/docs/http-cookies.html\n\n" +
+		"#HttpOnly_.example.com\tTRUE\t/\tFALSE\t0\tsession\tabc\n" +
+		"www.example.com\tFALSE\t/\tTRUE\t0\tlang\ten\n" +
+		"example.com\tFALSE\t/\tFALSE\t" + strconv.FormatInt(past, 10) + "\texpired\t1\n"
+
+	jar := sreq.NewCookieJar()
+	if err := jar.Load(strings.NewReader(data), sreq.CookieNetscape); err != nil {
+		t.Fatal(err)
+	}
+	if got := cookieString(jar.Cookies(mustParseURL(t, "https://www.example.com/"))); got != "session=abc; lang=en" {
+		t.Errorf("CookieJar Netscape cookies got: %q", got)
+	}
+
+	if err := jar.Load(strings.NewReader("example.com\tFALSE\t/\n"), sreq.CookieNetscape); err == nil {
+		t.Error("CookieJar malformed Netscape file unchecked")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := jar.Save(buf, sreq.CookieNetscape); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "#HttpOnly_.example.com\tTRUE\t/\tFALSE\t0\tsession\tabc\n") {
+		t.Errorf("CookieJar Netscape file got: %s", buf.String())
+	}
+}
+
+func TestCookieJar_Client(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "token", Value: "sreq", MaxAge: 3600})
+			return
+		}
+		if c, err := r.Cookie("token"); err != nil || c.Value != "sreq" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	jar := sreq.NewCookieJar()
+	if _, err := sreq.New(&http.Client{Jar: jar}).Get(ts.URL + "/login").EnsureStatusOk().Resolve(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := jar.Save(buf, sreq.CookieJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart with the saved cookies.
+	restored := sreq.NewCookieJar()
+	if err := restored.Load(buf, sreq.CookieJSON); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sreq.New(&http.Client{Jar: restored}).Get(ts.URL + "/profile").EnsureStatusOk().Resolve(); err != nil {
+		t.Error(err)
+	}
+}
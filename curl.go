@@ -0,0 +1,652 @@
+package sreq
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	stdurl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Curl returns the curl command line equivalent to the HTTP request
+// built with the given method, url and request options.
+func Curl(method string, url string, opts ...RequestOption) (string, error) {
+	return std.Curl(method, url, opts...)
+}
+
+// Curl returns the curl command line equivalent to the HTTP request
+// built with the given method, url and request options, the default request options
+// and the cookies from the cookie jar of c are also taken into account.
+func (c *Client) Curl(method string, url string, opts ...RequestOption) (string, error) {
+	httpReq, err := c.NewRequest(method, url, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	if c.C.Jar != nil {
+		for _, cookie := range c.C.Jar.Cookies(httpReq.URL) {
+			httpReq.AddCookie(cookie)
+		}
+	}
+	return ToCurl(httpReq)
+}
+
+// ToCurl returns the curl command line equivalent to httpReq.
+// The payload of httpReq is read and restored, so httpReq can still be sent afterwards.
+// A binary or encoded payload can't be expressed in the command line, it's referred to
+// as "--data-binary @-", i.e. it should be piped to the standard input of curl.
+func ToCurl(httpReq *http.Request) (string, error) {
+	httpReq, err := applyDeferred(httpReq)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"curl"}
+	switch httpReq.Method {
+	case MethodGet:
+	case MethodHead:
+		args = append(args, "--head")
+	default:
+		args = append(args, "-X", httpReq.Method)
+	}
+	args = append(args, shellQuote(httpReq.URL.String()))
+
+	header := httpReq.Header.Clone()
+	header.Del("Content-Length")
+	if httpReq.Host != "" && httpReq.Host != httpReq.URL.Host {
+		header.Set("Host", httpReq.Host)
+	}
+
+	if username, password, ok := httpReq.BasicAuth(); ok {
+		header.Del("Authorization")
+		args = append(args, "-u", shellQuote(username+":"+password))
+	}
+
+	if cookies := header.Get("Cookie"); cookies != "" {
+		header.Del("Cookie")
+		args = append(args, "-b", shellQuote(cookies))
+	}
+
+	data, err := curlData(httpReq, header)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			args = append(args, "-H", shellQuote(k+": "+v))
+		}
+	}
+
+	args = append(args, data...)
+	return strings.Join(args, " "), nil
+}
+
+// curlData returns the curl arguments of the payload of httpReq,
+// the headers implied by them are removed from header.
+func curlData(httpReq *http.Request, header http.Header) ([]string, error) {
+	b, err := payload(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		args, err := curlForm(b, params["boundary"])
+		if err == nil {
+			header.Del("Content-Type")
+			return args, nil
+		}
+	case "application/x-www-form-urlencoded":
+		header.Del("Content-Type")
+	}
+
+	if ce := header.Get("Content-Encoding"); binaryData(b) || (ce != "" && ce != "identity") {
+		return []string{"--data-binary", "@-"}, nil
+	}
+	return []string{"--data-raw", shellQuote(string(b))}, nil
+}
+
+// binaryData reports whether b can't be expressed as a string argument of the shell.
+func binaryData(b []byte) bool {
+	return !utf8.Valid(b) || bytes.IndexByte(b, 0) >= 0
+}
+
+func curlForm(b []byte, boundary string) ([]string, error) {
+	var args []string
+	mr := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return args, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if fileName := part.FileName(); fileName != "" {
+			// The content of the file can't be expressed, refer to it by the file name.
+			value := part.FormName() + "=@" + fileName
+			if contentType := part.Header.Get("Content-Type"); contentType != "" && contentType != "application/octet-stream" {
+				value += ";type=" + contentType
+			}
+			args = append(args, "-F", shellQuote(value))
+			continue
+		}
+
+		value, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--form-string", shellQuote(part.FormName()+"="+string(value)))
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// ParseCurl parses a curl command line, returns the method, the URL and the request options
+// equivalent to it, which can be fed to Client.Request.
+// The options concerning the transport, such as -L, -k and -s, are ignored since they're
+// the business of the client, and other options that sreq can't express are reported as errors.
+func ParseCurl(cmd string) (method string, url string, opts []RequestOption, err error) {
+	args, err := shellSplit(cmd)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return "", "", nil, errors.New("sreq: not a curl command")
+	}
+
+	p := &curlParser{
+		headers: make(MultiHeaders),
+	}
+	if err = p.parse(args[1:]); err != nil {
+		return "", "", nil, err
+	}
+	return p.build()
+}
+
+type curlParser struct {
+	method     string
+	url        string
+	head       bool
+	get        bool
+	compressed bool
+	headers    MultiHeaders
+	removed    []string
+	data       []string
+	form       *Multipart
+	user       *string
+	cookies    []*http.Cookie
+}
+
+// curlFlags maps the supported curl options to whether they take an argument.
+var curlFlags = map[string]bool{
+	"-X": true, "--request": true,
+	"-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-ascii": true, "--data-binary": true, "--data-raw": true, "--data-urlencode": true,
+	"-F": true, "--form": true, "--form-string": true,
+	"-u": true, "--user": true,
+	"-b": true, "--cookie": true,
+	"-A": true, "--user-agent": true,
+	"-e": true, "--referer": true, "--url": true,
+	"-G": false, "--get": false,
+	"-I": false, "--head": false, "--compressed": false,
+
+	// Ignored.
+	"-s": false, "--silent": false,
+	"-S": false, "--show-error": false,
+	"-L": false, "--location": false,
+	"-k": false, "--insecure": false,
+	"-i": false, "--include": false,
+	"-v": false, "--verbose": false,
+	"-g": false, "--globoff": false,
+}
+
+func (p *curlParser) parse(args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "" || arg[0] != '-' || arg == "-" {
+			if p.url != "" {
+				return fmt.Errorf("sreq: multiple URLs in curl command: %q", arg)
+			}
+			p.url = arg
+			continue
+		}
+
+		flag, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			// Short options may be combined like -sSL or take the argument like -XPOST.
+			flag = arg[:2]
+			if curlFlags[flag] {
+				value, hasValue = arg[2:], true
+			} else {
+				for _, c := range arg[1:] {
+					if takesArg, ok := curlFlags["-"+string(c)]; !ok || takesArg {
+						return fmt.Errorf("sreq: unsupported curl option: %q", arg)
+					}
+					if err := p.apply("-"+string(c), ""); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+
+		takesArg, ok := curlFlags[flag]
+		if !ok {
+			return fmt.Errorf("sreq: unsupported curl option: %q", flag)
+		}
+		if takesArg && !hasValue {
+			i++
+			if i >= len(args) {
+				return fmt.Errorf("sreq: curl option %q requires an argument", flag)
+			}
+			value = args[i]
+		}
+		if err := p.apply(flag, value); err != nil {
+			return err
+		}
+	}
+
+	if p.url == "" {
+		return errors.New("sreq: no URL in curl command")
+	}
+	return nil
+}
+
+func (p *curlParser) apply(flag string, value string) error {
+	switch flag {
+	case "-X", "--request":
+		p.method = value
+	case "-H", "--header":
+		p.header(value)
+	case "-d", "--data", "--data-ascii":
+		if strings.HasPrefix(value, "@") {
+			b, err := readCurlFile(value[1:])
+			if err != nil {
+				return err
+			}
+			value = strings.NewReplacer("\r", "", "\n", "").Replace(string(b))
+		}
+		p.data = append(p.data, value)
+	case "--data-binary":
+		if strings.HasPrefix(value, "@") {
+			b, err := readCurlFile(value[1:])
+			if err != nil {
+				return err
+			}
+			value = string(b)
+		}
+		p.data = append(p.data, value)
+	case "--data-raw":
+		p.data = append(p.data, value)
+	case "--data-urlencode":
+		v, err := curlURLEncode(value)
+		if err != nil {
+			return err
+		}
+		p.data = append(p.data, v)
+	case "-F", "--form", "--form-string":
+		return p.formField(value, flag == "--form-string")
+	case "-u", "--user":
+		p.user = &value
+	case "-b", "--cookie":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("sreq: curl cookie file not supported: %q", value)
+		}
+		p.cookies = append(p.cookies, parseCookieHeader(value)...)
+	case "-A", "--user-agent":
+		p.header("User-Agent: " + value)
+	case "-e", "--referer":
+		p.header("Referer: " + value)
+	case "--url":
+		if p.url != "" {
+			return fmt.Errorf("sreq: multiple URLs in curl command: %q", value)
+		}
+		p.url = value
+	case "-G", "--get":
+		p.get = true
+	case "-I", "--head":
+		p.head = true
+	case "--compressed":
+		p.compressed = true
+	}
+	return nil
+}
+
+// header handles the curl header syntax, "Name: value" adds a header,
+// "Name:" removes it and "Name;" adds it with an empty value.
+func (p *curlParser) header(value string) {
+	if i := strings.Index(value, ":"); i >= 0 {
+		name, v := http.CanonicalHeaderKey(strings.TrimSpace(value[:i])), strings.TrimSpace(value[i+1:])
+		if v == "" {
+			p.headers.Del(name)
+			p.removed = append(p.removed, name)
+			return
+		}
+		p.headers.Add(name, v)
+		return
+	}
+	if strings.HasSuffix(value, ";") {
+		p.headers.Add(http.CanonicalHeaderKey(strings.TrimSpace(strings.TrimSuffix(value, ";"))), "")
+	}
+}
+
+// formField handles the curl form syntax, "name=value" adds a field,
+// "name=@path;type=...;filename=..." adds a file and "name=<path" adds a field with the file content.
+func (p *curlParser) formField(value string, literal bool) error {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return fmt.Errorf("sreq: illegal curl form field: %q", value)
+	}
+	name, value := value[:i], value[i+1:]
+	if p.form == nil {
+		p.form = NewMultipart()
+	}
+
+	if literal || (!strings.HasPrefix(value, "@") && !strings.HasPrefix(value, "<")) {
+		p.form.AddField(name, value)
+		return nil
+	}
+
+	params := strings.Split(value[1:], ";")
+	path := params[0]
+	if value[0] == '<' {
+		b, err := readCurlFile(path)
+		if err != nil {
+			return err
+		}
+		p.form.AddField(name, string(b))
+		return nil
+	}
+
+	file := FileFromPath(name, path)
+	for _, param := range params[1:] {
+		param = strings.TrimSpace(param)
+		switch {
+		case strings.HasPrefix(param, "type="):
+			file.ContentType = strings.TrimPrefix(param, "type=")
+		case strings.HasPrefix(param, "filename="):
+			file.FileName = strings.Trim(strings.TrimPrefix(param, "filename="), `"`)
+		}
+	}
+	p.form.AddFile(file)
+	return nil
+}
+
+func (p *curlParser) build() (string, string, []RequestOption, error) {
+	if p.form != nil && len(p.data) != 0 {
+		return "", "", nil, errors.New("sreq: curl form and data can not be used together")
+	}
+
+	url := p.url
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+
+	method := MethodGet
+	switch {
+	case p.head:
+		method = MethodHead
+	case p.get:
+	case p.form != nil || len(p.data) != 0:
+		method = MethodPost
+	}
+	if p.method != "" {
+		method = p.method
+	}
+
+	var opts []RequestOption
+	data := strings.Join(p.data, "&")
+	switch {
+	case p.get && len(p.data) != 0:
+		if strings.Contains(url, "?") {
+			url += "&" + data
+		} else {
+			url += "?" + data
+		}
+	case len(p.data) != 0:
+		opts = append(opts, WithRaw([]byte(data), "application/x-www-form-urlencoded"))
+	case p.form != nil:
+		opts = append(opts, WithMultipart(p.form))
+		if ct := p.headers.Get("Content-Type"); strings.HasPrefix(ct, "multipart/form-data") {
+			// The boundary is generated by WithMultipart.
+			p.headers.Del("Content-Type")
+		}
+	}
+
+	if p.compressed {
+		// Let the transport negotiate the encoding and decompress the response transparently.
+		p.headers.Del("Accept-Encoding")
+	}
+
+	if host := p.headers.Get("Host"); host != "" {
+		// net/http ignores the Host header, it must be set to http.Request.Host.
+		p.headers.Del("Host")
+		opts = append(opts, WithHost(host))
+	}
+	if p.user != nil {
+		username, password := *p.user, ""
+		if i := strings.Index(username, ":"); i >= 0 {
+			username, password = username[:i], username[i+1:]
+		}
+		opts = append(opts, WithBasicAuth(username, password))
+	}
+	if len(p.cookies) != 0 {
+		opts = append(opts, WithCookies(p.cookies...))
+	}
+	if len(p.headers) != 0 {
+		opts = append(opts, WithMultiHeaders(p.headers))
+	}
+	if len(p.removed) != 0 {
+		headers, removed := p.headers, p.removed
+		opts = append(opts, func(hr *http.Request) (*http.Request, error) {
+			for _, name := range removed {
+				if headers[name] != nil {
+					continue
+				}
+				if name == "User-Agent" {
+					// An empty User-Agent prevents net/http from sending its default one.
+					hr.Header.Set(name, "")
+					continue
+				}
+				hr.Header.Del(name)
+			}
+			return hr, nil
+		})
+	}
+
+	return method, url, opts, nil
+}
+
+func readCurlFile(filename string) ([]byte, error) {
+	if filename == "-" {
+		return nil, errors.New("sreq: curl data from stdin not supported")
+	}
+	return ioutil.ReadFile(filename)
+}
+
+// curlURLEncode handles the --data-urlencode syntax: "content", "=content",
+// "name=content", "@filename" and "name@filename".
+func curlURLEncode(value string) (string, error) {
+	var name, content string
+	if i := strings.IndexAny(value, "=@"); i < 0 {
+		content = value
+	} else if value[i] == '=' {
+		name, content = value[:i], value[i+1:]
+	} else {
+		b, err := readCurlFile(value[i+1:])
+		if err != nil {
+			return "", err
+		}
+		name, content = value[:i], string(b)
+	}
+
+	content = stdurl.QueryEscape(content)
+	if name == "" {
+		return content, nil
+	}
+	return name + "=" + content, nil
+}
+
+// parseCookieHeader parses cookies in the form of "name1=value1; name2=value2".
+func parseCookieHeader(v string) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, pair := range strings.Split(v, ";") {
+		pair = strings.TrimSpace(pair)
+		i := strings.Index(pair, "=")
+		if i <= 0 {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{
+			Name:  pair[:i],
+			Value: pair[i+1:],
+		})
+	}
+	return cookies
+}
+
+// shellSplit splits a command line into words like a POSIX shell does, it supports
+// single quotes, double quotes, ANSI-C quotes ($'...'), backslash escapes and line continuations.
+func shellSplit(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		runes   = []rune(s)
+		n       = len(runes)
+		errQuot = errors.New("sreq: unterminated quote in curl command")
+	)
+
+	for i := 0; i < n; i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '\\':
+			i++
+			if i >= n {
+				break
+			}
+			if runes[i] == '\n' {
+				// Line continuation.
+				continue
+			}
+			if runes[i] == '\r' && i+1 < n && runes[i+1] == '\n' {
+				i++
+				continue
+			}
+			inWord = true
+			word.WriteRune(runes[i])
+		case r == '\'':
+			inWord = true
+			j := i + 1
+			for j < n && runes[j] != '\'' {
+				j++
+			}
+			if j >= n {
+				return nil, errQuot
+			}
+			word.WriteString(string(runes[i+1 : j]))
+			i = j
+		case r == '"':
+			inWord = true
+			i++
+			for ; i < n && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < n {
+					switch runes[i+1] {
+					case '"', '\\', '$', '`':
+						i++
+					case '\n':
+						i++
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= n {
+				return nil, errQuot
+			}
+		case r == '$' && i+1 < n && runes[i+1] == '\'':
+			inWord = true
+			i += 2
+			for ; i < n && runes[i] != '\''; i++ {
+				if runes[i] != '\\' || i+1 >= n {
+					word.WriteRune(runes[i])
+					continue
+				}
+				i++
+				switch runes[i] {
+				case 'n':
+					word.WriteByte('\n')
+				case 'r':
+					word.WriteByte('\r')
+				case 't':
+					word.WriteByte('\t')
+				case 'x', 'u':
+					size := 2
+					if runes[i] == 'u' {
+						size = 4
+					}
+					j := i + 1
+					for j < n && j <= i+size && isHexDigit(runes[j]) {
+						j++
+					}
+					if j == i+1 {
+						word.WriteRune('\\')
+						word.WriteRune(runes[i])
+						continue
+					}
+					code, _ := strconv.ParseUint(string(runes[i+1:j]), 16, 32)
+					if runes[i] == 'x' {
+						word.WriteByte(byte(code))
+					} else {
+						word.WriteRune(rune(code))
+					}
+					i = j - 1
+				default:
+					word.WriteRune(runes[i])
+				}
+			}
+			if i >= n {
+				return nil, errQuot
+			}
+		default:
+			inWord = true
+			word.WriteRune(r)
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func isHexDigit(r rune) bool {
+	return ('0' <= r && r <= '9') || ('a' <= r && r <= 'f') || ('A' <= r && r <= 'F')
+}
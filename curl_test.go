@@ -0,0 +1,328 @@
+package sreq_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestCurl(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		opts   []sreq.RequestOption
+		want   string
+	}{
+		{
+			method: sreq.MethodGet,
+			url:    "http://httpbin.org/get",
+			opts: []sreq.RequestOption{
+				sreq.WithMultiQuery(sreq.MultiParams{
+					"id": {"1", "2"},
+				}),
+				sreq.WithHeaders(sreq.Headers{
+					"Referer": "http://httpbin.org",
+				}),
+				sreq.WithCookies(&http.Cookie{
+					Name:  "uid",
+					Value: "10086",
+				}),
+				sreq.WithBasicAuth("admin", "pass"),
+			},
+			want: `curl 'http://httpbin.org/get?id=1&id=2' -u 'admin:pass' -b 'uid=10086' ` +
+				`-H 'Referer: http://httpbin.org' -H 'User-Agent: sreq ` + sreq.Version + `'`,
+		},
+		{
+			method: sreq.MethodPost,
+			url:    "http://httpbin.org/post",
+			opts: []sreq.RequestOption{
+				sreq.WithJSON(sreq.JSON{
+					"msg": "it's sreq",
+				}, false),
+			},
+			want: `curl -X POST 'http://httpbin.org/post' -H 'Content-Type: application/json' ` +
+				`-H 'User-Agent: sreq ` + sreq.Version + `' --data-raw '{"msg":"it'\''s sreq"}` + "\n'",
+		},
+		{
+			method: sreq.MethodPut,
+			url:    "http://httpbin.org/put",
+			opts: []sreq.RequestOption{
+				sreq.WithForm(sreq.Form{
+					"key1": "value1",
+					"key2": "value2",
+				}),
+			},
+			want: `curl -X PUT 'http://httpbin.org/put' -H 'User-Agent: sreq ` + sreq.Version + `' ` +
+				`--data-raw 'key1=value1&key2=value2'`,
+		},
+	}
+
+	req := sreq.New(nil)
+	for _, test := range tests {
+		got, err := req.Curl(test.method, test.url, test.opts...)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Curl got: %s, want: %s", got, test.want)
+		}
+	}
+}
+
+func TestToCurl_Multipart(t *testing.T) {
+	httpReq, err := http.NewRequest(sreq.MethodPost, "http://httpbin.org/post", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := sreq.FileFromBytes("file", "hello.txt", []byte("hello world"))
+	file.ContentType = "text/plain"
+	httpReq, err = sreq.WithMultipart(sreq.NewMultipart().
+		AddField("key", "value").
+		AddFile(file),
+	)(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sreq.ToCurl(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `curl -X POST 'http://httpbin.org/post' --form-string 'key=value' -F 'file=@hello.txt;type=text/plain'`
+	if got != want {
+		t.Errorf("ToCurl got: %s, want: %s", got, want)
+	}
+	if strings.Contains(got, "boundary") {
+		t.Error("ToCurl kept the multipart boundary")
+	}
+}
+
+func TestToCurl_Binary(t *testing.T) {
+	req := sreq.New(nil)
+	tests := []struct {
+		opts []sreq.RequestOption
+		want string
+	}{
+		{
+			opts: []sreq.RequestOption{
+				sreq.WithRaw([]byte{0x1f, 0x8b, 0x08, 0x00, 0xff}, "application/octet-stream"),
+			},
+			want: `curl -X POST 'http://httpbin.org/post' -H 'Content-Type: application/octet-stream' ` +
+				`-H 'User-Agent: sreq ` + sreq.Version + `' --data-binary @-`,
+		},
+		{
+			opts: []sreq.RequestOption{
+				sreq.WithText("hello world"),
+				sreq.WithHeaders(sreq.Headers{
+					"Content-Encoding": "br",
+				}),
+			},
+			want: `curl -X POST 'http://httpbin.org/post' -H 'Content-Encoding: br' ` +
+				`-H 'Content-Type: text/plain' -H 'User-Agent: sreq ` + sreq.Version + `' --data-binary @-`,
+		},
+	}
+	for _, test := range tests {
+		got, err := req.Curl(sreq.MethodPost, "http://httpbin.org/post", test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("Curl got: %s, want: %s", got, test.want)
+		}
+	}
+}
+
+type echoResponse struct {
+	Method string              `json:"method"`
+	Host   string              `json:"host"`
+	URL    string              `json:"url"`
+	Header map[string][]string `json:"header"`
+	Body   string              `json:"body"`
+}
+
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(&echoResponse{
+			Method: r.Method,
+			Host:   r.Host,
+			URL:    r.URL.String(),
+			Header: r.Header,
+			Body:   string(b),
+		})
+	}))
+}
+
+func TestParseCurl(t *testing.T) {
+	ts := echoServer()
+	defer ts.Close()
+
+	cmd := `curl '` + ts.URL + `/post?id=1' \
+  -H 'accept: application/json' \
+  -H $'x-note: it\'s sreq' \
+  -H 'accept-encoding: gzip, deflate, br' \
+  -H "User-Agent:" \
+  -b 'uid=10086; lang=en' \
+  -u admin:pass \
+  --data-raw 'key1=value1' \
+  --data-urlencode 'key2=hello world' \
+  --compressed`
+
+	method, url, opts, err := sreq.ParseCurl(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != sreq.MethodPost {
+		t.Errorf("ParseCurl method got: %s, want: %s", method, sreq.MethodPost)
+	}
+
+	resp := new(echoResponse)
+	err = sreq.New(nil).
+		Request(method, url, opts...).
+		EnsureStatusOk().
+		JSON(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.URL != "/post?id=1" {
+		t.Errorf("ParseCurl URL got: %s", resp.URL)
+	}
+	if resp.Body != "key1=value1&key2=hello+world" {
+		t.Errorf("ParseCurl body got: %s", resp.Body)
+	}
+	header := http.Header(resp.Header)
+	if header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("ParseCurl Content-Type got: %s", header.Get("Content-Type"))
+	}
+	if header.Get("Accept") != "application/json" || header.Get("X-Note") != "it's sreq" {
+		t.Errorf("ParseCurl headers got: %v", header)
+	}
+	if header.Get("Accept-Encoding") != "gzip" {
+		t.Errorf("ParseCurl Accept-Encoding got: %s, want: gzip", header.Get("Accept-Encoding"))
+	}
+	if _, ok := header["User-Agent"]; ok {
+		t.Errorf("ParseCurl User-Agent not removed: %s", header.Get("User-Agent"))
+	}
+	if header.Get("Cookie") != "uid=10086; lang=en" {
+		t.Errorf("ParseCurl cookies got: %s", header.Get("Cookie"))
+	}
+	if header.Get("Authorization") != "Basic YWRtaW46cGFzcw==" {
+		t.Errorf("ParseCurl basic auth got: %s", header.Get("Authorization"))
+	}
+}
+
+func TestParseCurl_Host(t *testing.T) {
+	ts := echoServer()
+	defer ts.Close()
+
+	method, url, opts, err := sreq.ParseCurl(`curl ` + ts.URL + ` -H 'Host: example.com'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(echoResponse)
+	err = sreq.New(nil).
+		Request(method, url, opts...).
+		EnsureStatusOk().
+		JSON(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Host != "example.com" {
+		t.Errorf("ParseCurl Host got: %s, want: %s", resp.Host, "example.com")
+	}
+}
+
+func TestParseCurl_Get(t *testing.T) {
+	method, url, opts, err := sreq.ParseCurl(`curl -sSLG "http://example.com/get" -d id=1 -XPOST -d "name=\"sreq\""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != sreq.MethodPost || url != `http://example.com/get?id=1&name="sreq"` || len(opts) != 0 {
+		t.Errorf("ParseCurl got: %s %s %d", method, url, len(opts))
+	}
+
+	method, _, _, err = sreq.ParseCurl(`curl -I example.com`)
+	if err != nil || method != sreq.MethodHead {
+		t.Errorf("ParseCurl --head got: %s, %v", method, err)
+	}
+}
+
+func TestParseCurl_Form(t *testing.T) {
+	ts := multipartServer()
+	defer ts.Close()
+
+	method, url, opts, err := sreq.ParseCurl(`curl ` + ts.URL +
+		` -H 'Content-Type: multipart/form-data'` +
+		` -F title=reports --form-string 'tag=@a'` +
+		` -F 'doc=@./testdata/testfile1.txt;type=text/plain;filename=doc.txt'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := new(multipartResponse)
+	err = sreq.New(nil).
+		Request(method, url, opts...).
+		EnsureStatusOk().
+		JSON(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Fields["title"][0] != "reports" || resp.Fields["tag"][0] != "@a" {
+		t.Errorf("ParseCurl form fields got: %v", resp.Fields)
+	}
+	if docs := resp.Files["doc"]; len(docs) != 1 || docs[0].FileName != "doc.txt" || docs[0].ContentType != "text/plain" {
+		t.Errorf("ParseCurl form files got: %v", resp.Files)
+	}
+}
+
+func TestParseCurl_RoundTrip(t *testing.T) {
+	want, err := sreq.New(nil).Curl(sreq.MethodPost, "http://example.com/post",
+		sreq.WithHeaders(sreq.Headers{
+			"X-Note": "it's sreq",
+		}),
+		sreq.WithJSON(sreq.JSON{
+			"msg": "hello world",
+		}, false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	method, url, opts, err := sreq.ParseCurl(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sreq.New(nil).Curl(method, url, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("ParseCurl round trip got: %s, want: %s", got, want)
+	}
+}
+
+func TestParseCurl_Error(t *testing.T) {
+	for _, cmd := range []string{
+		`wget http://example.com`,
+		`curl`,
+		`curl 'http://example.com`,
+		`curl http://example.com -H`,
+		`curl http://example.com --proxy http://127.0.0.1:8080`,
+		`curl http://example.com -d a=1 -F b=2`,
+		`curl http://example.com -b cookies.txt`,
+	} {
+		if _, _, _, err := sreq.ParseCurl(cmd); err == nil {
+			t.Errorf("ParseCurl %q error unchecked", cmd)
+		}
+	}
+}
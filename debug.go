@@ -0,0 +1,208 @@
+package sreq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+type (
+	// Logger is the interface that Dumper logs to, *log.Logger implements it.
+	Logger interface {
+		Printf(format string, v ...interface{})
+	}
+
+	// Dumper dumps HTTP requests and responses on the wire for debugging.
+	Dumper struct {
+		// Logger specifies the logger that dumps are written to.
+		Logger Logger
+
+		// MaxBodySize specifies the max bytes of the request and response bodies to dump,
+		// bodies are omitted if it's negative.
+		MaxBodySize int64
+
+		// RedactHeaders specifies the headers whose values are hidden from dumps.
+		RedactHeaders []string
+	}
+
+	dumpTransport struct {
+		dumper *Dumper
+		next   http.RoundTripper
+	}
+
+	// dumpBody logs the leading bytes of a response body as it's read by the caller.
+	dumpBody struct {
+		io.ReadCloser
+		dumper *Dumper
+		title  string
+		buf    bytes.Buffer
+		once   sync.Once
+	}
+)
+
+// NewDumper returns a dumper which logs to logger, dumps 4096 bytes of bodies at most,
+// and redacts Authorization, Proxy-Authorization, Cookie and Set-Cookie headers.
+func NewDumper(logger Logger) *Dumper {
+	return &Dumper{
+		Logger:        logger,
+		MaxBodySize:   4096,
+		RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+	}
+}
+
+// Debug enables debug mode, which dumps HTTP requests and responses to logger.
+func Debug(logger Logger) {
+	std.Debug(logger)
+}
+
+// Debug enables debug mode, which dumps HTTP requests and responses to logger.
+// The dumps are taken on the transport of c, so redirects and cookies from the jar are included.
+// Calling it again replaces the logger.
+func (c *Client) Debug(logger Logger) {
+	c.mux.Lock()
+	transport := c.C.Transport
+	if dt, ok := transport.(*dumpTransport); ok {
+		transport = dt.next
+	}
+	c.C.Transport = NewDumper(logger).Transport(transport)
+	c.mux.Unlock()
+}
+
+// Transport returns an HTTP transport which dumps the round trips of next,
+// http.DefaultTransport is used if next is nil.
+func (d *Dumper) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &dumpTransport{
+		dumper: d,
+		next:   next,
+	}
+}
+
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d := t.dumper
+	dump, body := d.dumpRequest(req)
+	if body != req.Body {
+		r := *req
+		r.Body = body
+		req = &r
+	}
+	d.Logger.Printf("%s", dump)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		d.Logger.Printf("sreq: %s %s: %v", req.Method, redactURL(req.URL), err)
+		return nil, err
+	}
+
+	dump, resp.Body = d.dumpResponse(req, resp)
+	d.Logger.Printf("%s", dump)
+	return resp, nil
+}
+
+func (d *Dumper) redact(header http.Header) http.Header {
+	header = header.Clone()
+	for _, k := range d.RedactHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(k)]; ok {
+			header.Set(k, "[REDACTED]")
+		}
+	}
+	return header
+}
+
+// dumpRequest dumps req, and returns the body to send which may be wrapped for peeking.
+func (d *Dumper) dumpRequest(req *http.Request) ([]byte, io.ReadCloser) {
+	// The body of r is replaced by a dummy one without being read.
+	r := *req
+	r.Header = d.redact(req.Header)
+	dump, err := httputil.DumpRequestOut(&r, false)
+	if err != nil {
+		return []byte(fmt.Sprintf("sreq: dump request failed: %v", err)), req.Body
+	}
+
+	body := req.Body
+	if d.MaxBodySize < 0 || body == nil || body == http.NoBody {
+		return dump, body
+	}
+
+	var snippet []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			snippet, _ = ioutil.ReadAll(io.LimitReader(rc, d.MaxBodySize+1))
+			rc.Close()
+		}
+	} else {
+		snippet, body = peekBody(body, d.MaxBodySize+1)
+	}
+	return d.appendBody(dump, snippet), body
+}
+
+// dumpResponse dumps the head of resp, and returns its body for reading which may be wrapped
+// to dump the body as it's read, so that streaming responses reach the caller without delay.
+func (d *Dumper) dumpResponse(req *http.Request, resp *http.Response) ([]byte, io.ReadCloser) {
+	r := *resp
+	r.Header = d.redact(resp.Header)
+	r.Body = nil
+	dump, err := httputil.DumpResponse(&r, false)
+	if err != nil {
+		return []byte(fmt.Sprintf("sreq: dump response failed: %v", err)), resp.Body
+	}
+
+	if d.MaxBodySize < 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return dump, resp.Body
+	}
+	return dump, &dumpBody{
+		ReadCloser: resp.Body,
+		dumper:     d,
+		title:      fmt.Sprintf("sreq: response body of %s %s:\n", req.Method, redactURL(req.URL)),
+	}
+}
+
+func (b *dumpBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if remaining := b.dumper.MaxBodySize + 1 - int64(b.buf.Len()); remaining > 0 {
+		if int64(n) > remaining {
+			b.buf.Write(p[:remaining])
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err != nil || int64(b.buf.Len()) > b.dumper.MaxBodySize {
+		b.flush()
+	}
+	return n, err
+}
+
+func (b *dumpBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.flush()
+	return err
+}
+
+// flush logs the body read so far, only once.
+func (b *dumpBody) flush() {
+	b.once.Do(func() {
+		b.dumper.Logger.Printf("%s", b.dumper.appendBody([]byte(b.title), b.buf.Bytes()))
+		b.buf = bytes.Buffer{}
+	})
+}
+
+func (d *Dumper) appendBody(dump []byte, snippet []byte) []byte {
+	truncated := int64(len(snippet)) > d.MaxBodySize
+	if truncated {
+		snippet = snippet[:d.MaxBodySize]
+	}
+
+	var buf bytes.Buffer
+	buf.Write(dump)
+	buf.Write(snippet)
+	if truncated {
+		buf.WriteString("\n... (truncated)")
+	}
+	return buf.Bytes()
+}
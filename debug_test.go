@@ -0,0 +1,192 @@
+package sreq_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestClient_Debug(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{
+			Name:  "uid",
+			Value: "10086",
+		})
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	buf := new(bytes.Buffer)
+	req := sreq.New(nil)
+	req.Debug(log.New(buf, "", 0))
+	if err := req.SetResponseHeaderTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	text := strings.Repeat("x", 5000)
+	data, err := req.
+		Post(ts.URL,
+			sreq.WithText(text),
+			sreq.WithBearerToken("secret"),
+		).
+		EnsureStatusOk().
+		Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != text {
+		t.Error("Response body corrupted by Dumper")
+	}
+
+	dump := buf.String()
+	for _, want := range []string{
+		"POST / HTTP/1.1",
+		"Authorization: [REDACTED]",
+		"HTTP/1.1 200 OK",
+		"Set-Cookie: [REDACTED]",
+		strings.Repeat("x", 4096) + "\n... (truncated)",
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("Client_Debug dump missing: %q", want)
+		}
+	}
+	if strings.Contains(dump, "secret") || strings.Contains(dump, "10086") {
+		t.Error("Client_Debug dump not redacted")
+	}
+}
+
+func TestDumper_Stream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	buf := new(bytes.Buffer)
+	dumper := sreq.NewDumper(log.New(buf, "", 0))
+	dumper.MaxBodySize = 8
+	req := sreq.New(&http.Client{
+		Transport: dumper.Transport(nil),
+	})
+
+	// Multipart payload from a reader is streamed and can't be replayed.
+	data, err := req.
+		Post(ts.URL,
+			sreq.WithMultipart(sreq.NewMultipart().
+				AddFile(sreq.FileFromReader("file", "hello.txt", strings.NewReader("hello world"))),
+			),
+		).
+		EnsureStatusOk().
+		Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(data, "hello world") {
+		t.Error("Request body corrupted by Dumper")
+	}
+	if !strings.Contains(buf.String(), "Transfer-Encoding: chunked") {
+		t.Errorf("Dumper dump got: %s", buf.String())
+	}
+}
+
+func TestDumper_StreamResponse(t *testing.T) {
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+		<-unblock
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	buf := new(bytes.Buffer)
+	req := sreq.New(nil)
+	req.Debug(log.New(buf, "", 0))
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := req.Get(ts.URL).Resolve()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer resp.Body.Close()
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		if err == nil && line != "data: hello\n" {
+			err = fmt.Errorf("got %q", line)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Streaming response blocked by Dumper")
+	}
+	if !strings.Contains(buf.String(), "Content-Type: text/event-stream") {
+		t.Errorf("Dumper dump got: %s", buf.String())
+	}
+}
+
+func TestClient_Debug_Twice(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	first, second := new(bytes.Buffer), new(bytes.Buffer)
+	req := sreq.New(nil)
+	req.Debug(log.New(first, "", 0))
+	req.Debug(log.New(second, "", 0))
+	if err := req.SetResponseHeaderTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := req.Get(ts.URL).EnsureStatusOk().Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if first.Len() != 0 {
+		t.Errorf("Client_Debug replaced logger got: %s", first.String())
+	}
+	if n := strings.Count(second.String(), "GET / HTTP/1.1"); n != 1 {
+		t.Errorf("Client_Debug dumped the request %d times", n)
+	}
+}
+
+func TestDumper_Nested(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	inner, outer := new(bytes.Buffer), new(bytes.Buffer)
+	req := sreq.New(&http.Client{
+		Transport: sreq.NewDumper(log.New(outer, "", 0)).Transport(
+			sreq.NewDumper(log.New(inner, "", 0)).Transport(nil),
+		),
+	})
+	if err := req.SetResponseHeaderTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := req.Get(ts.URL).EnsureStatusOk().Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if inner.Len() == 0 || outer.Len() == 0 {
+		t.Error("configureTransport dropped nested dumpers")
+	}
+}
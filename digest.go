@@ -0,0 +1,261 @@
+package sreq
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type (
+	// DigestAuth authorizes HTTP requests with the Digest authentication defined in RFC 7616,
+	// it answers the 401 challenges automatically and reuses them for the subsequent requests
+	// to the same host, tracking the nonce count. It supports MD5, SHA-256 and their session variants
+	// with qop=auth. Attach it to a client with Client.Use(da.Middleware).
+	DigestAuth struct {
+		username   string
+		password   string
+		challenges map[string]*digestChallenge
+		mux        sync.Mutex
+	}
+
+	digestChallenge struct {
+		realm     string
+		nonce     string
+		opaque    string
+		algorithm string
+		qop       string
+		userhash  bool
+		stale     bool
+		nc        int
+	}
+)
+
+// NewDigestAuth returns a Digest authenticator of the given credentials.
+func NewDigestAuth(username string, password string) *DigestAuth {
+	return &DigestAuth{
+		username:   username,
+		password:   password,
+		challenges: make(map[string]*digestChallenge),
+	}
+}
+
+// Middleware authorizes the HTTP requests sent by next with Digest authentication.
+func (da *DigestAuth) Middleware(next Doer) Doer {
+	return DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+		host := httpReq.URL.Host
+		da.mux.Lock()
+		cached := da.challenges[host]
+		da.mux.Unlock()
+
+		authorized := httpReq
+		if cached != nil {
+			authorized = httpReq.Clone(httpReq.Context())
+			authorized.Header.Set("Authorization", da.authorization(cached, httpReq))
+		}
+
+		httpResp, err := next.Do(authorized)
+		if err != nil || httpResp.StatusCode != http.StatusUnauthorized {
+			return httpResp, err
+		}
+
+		// A cached challenge is replaced and answered again if the server issues a new nonce,
+		// many servers rotate nonces without reporting the old one is stale. The same nonce
+		// being rejected means the credentials are wrong.
+		challenge := parseDigestChallenge(httpResp.Header["Www-Authenticate"])
+		if challenge == nil || (cached != nil && !challenge.stale && challenge.nonce == cached.nonce) ||
+			rewindBody(httpReq) != nil {
+			return httpResp, nil
+		}
+
+		da.mux.Lock()
+		da.challenges[host] = challenge
+		da.mux.Unlock()
+
+		drainBody(&Response{R: httpResp})
+		authorized = httpReq.Clone(httpReq.Context())
+		authorized.Header.Set("Authorization", da.authorization(challenge, httpReq))
+		return next.Do(authorized)
+	})
+}
+
+// authorization returns the Authorization header answering the challenge c.
+func (da *DigestAuth) authorization(c *digestChallenge, httpReq *http.Request) string {
+	da.mux.Lock()
+	c.nc++
+	nc := fmt.Sprintf("%08x", c.nc)
+	da.mux.Unlock()
+
+	newHash := md5.New
+	if strings.HasPrefix(strings.ToUpper(c.algorithm), "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		hh := newHash()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	cnonce := digestCnonce()
+	uri := httpReq.URL.RequestURI()
+	ha1 := h(da.username + ":" + c.realm + ":" + da.password)
+	if strings.HasSuffix(strings.ToLower(c.algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(httpReq.Method + ":" + uri)
+
+	var response string
+	if c.qop == "" {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	}
+
+	username := da.username
+	if c.userhash {
+		username = h(da.username + ":" + c.realm)
+	}
+
+	params := []string{
+		"username=" + quoteDigest(username),
+		"realm=" + quoteDigest(c.realm),
+		"nonce=" + quoteDigest(c.nonce),
+		"uri=" + quoteDigest(uri),
+		"response=" + quoteDigest(response),
+	}
+	if c.algorithm != "" {
+		params = append(params, "algorithm="+c.algorithm)
+	}
+	if c.opaque != "" {
+		params = append(params, "opaque="+quoteDigest(c.opaque))
+	}
+	if c.qop != "" {
+		params = append(params, "qop="+c.qop, "nc="+nc, "cnonce="+quoteDigest(cnonce))
+	}
+	if c.userhash {
+		params = append(params, "userhash=true")
+	}
+	return "Digest " + strings.Join(params, ", ")
+}
+
+// parseDigestChallenge returns the supported Digest challenge with the strongest algorithm
+// from the WWW-Authenticate headers, nil if none.
+func parseDigestChallenge(headers []string) *digestChallenge {
+	var best *digestChallenge
+	for _, v := range headers {
+		for _, params := range parseAuthChallenges(v, "digest") {
+			c := &digestChallenge{
+				realm:     params["realm"],
+				nonce:     params["nonce"],
+				opaque:    params["opaque"],
+				algorithm: params["algorithm"],
+				userhash:  strings.EqualFold(params["userhash"], "true"),
+				stale:     strings.EqualFold(params["stale"], "true"),
+			}
+			if c.nonce == "" {
+				continue
+			}
+
+			switch strings.ToUpper(c.algorithm) {
+			case "", "MD5", "MD5-SESS", "SHA-256", "SHA-256-SESS":
+			default:
+				continue
+			}
+
+			if qop, ok := params["qop"]; ok {
+				for _, option := range strings.Split(qop, ",") {
+					if strings.TrimSpace(option) == "auth" {
+						c.qop = "auth"
+					}
+				}
+				if c.qop == "" {
+					// auth-int is not supported.
+					continue
+				}
+			}
+
+			if best == nil || (strings.HasPrefix(strings.ToUpper(c.algorithm), "SHA-256") &&
+				!strings.HasPrefix(strings.ToUpper(best.algorithm), "SHA-256")) {
+				best = c
+			}
+		}
+	}
+	return best
+}
+
+// parseAuthChallenges parses the challenges of the given scheme from a WWW-Authenticate header,
+// which may contain multiple challenges, the names of the params are in lower case.
+func parseAuthChallenges(v string, scheme string) []map[string]string {
+	var (
+		challenges []map[string]string
+		current    map[string]string
+	)
+	for {
+		v = strings.TrimLeft(v, " \t,")
+		if v == "" {
+			return challenges
+		}
+
+		i := strings.IndexAny(v, " \t=,")
+		if i < 0 {
+			i = len(v)
+		}
+		token := v[:i]
+		v = strings.TrimLeft(v[i:], " \t")
+
+		if !strings.HasPrefix(v, "=") {
+			// A new challenge begins with the scheme.
+			current = nil
+			if strings.EqualFold(token, scheme) {
+				current = make(map[string]string)
+				challenges = append(challenges, current)
+			}
+			continue
+		}
+
+		var value string
+		value, v = parseAuthParamValue(strings.TrimLeft(v[1:], " \t"))
+		if current != nil {
+			current[strings.ToLower(token)] = value
+		}
+	}
+}
+
+// parseAuthParamValue parses a token or a quoted string, returns it and the rest of s.
+func parseAuthParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		i := strings.IndexAny(s, " \t,")
+		if i < 0 {
+			return s, ""
+		}
+		return s[:i], s[i:]
+	}
+
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return sb.String(), s[i+1:]
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String(), ""
+}
+
+func quoteDigest(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func digestCnonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
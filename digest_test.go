@@ -0,0 +1,188 @@
+package sreq_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+var digestParamRegexp = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|([^,\s]+))`)
+
+type digestServer struct {
+	*httptest.Server
+	mux        sync.Mutex
+	nonce      int
+	lastNC     int64
+	challenges int
+	requests   []string
+
+	// silent reports the rotated nonce without stale=true.
+	silent bool
+}
+
+// newDigestServer returns a server which requires Digest authentication of admin:pass
+// with the given algorithms offered, the nonce is renewed for every 3 requests.
+func newDigestServer(algorithms ...string) *digestServer {
+	ds := new(digestServer)
+	ds.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ds.mux.Lock()
+		defer ds.mux.Unlock()
+
+		b, _ := ioutil.ReadAll(r.Body)
+		nonce := fmt.Sprintf("nonce%d", ds.nonce)
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Digest ") {
+			ds.challenge(w, algorithms, false)
+			return
+		}
+
+		params := make(map[string]string)
+		for _, m := range digestParamRegexp.FindAllStringSubmatch(auth, -1) {
+			params[m[1]] = m[2] + m[3]
+		}
+		if params["nonce"] != nonce {
+			ds.challenge(w, algorithms, !ds.silent)
+			return
+		}
+
+		var newHash func() hash.Hash = md5.New
+		if strings.HasPrefix(params["algorithm"], "SHA-256") {
+			newHash = sha256.New
+		}
+		h := func(s string) string {
+			hh := newHash()
+			hh.Write([]byte(s))
+			return hex.EncodeToString(hh.Sum(nil))
+		}
+
+		ha1 := h("admin:sreq:pass")
+		if strings.HasSuffix(params["algorithm"], "-sess") {
+			ha1 = h(ha1 + ":" + nonce + ":" + params["cnonce"])
+		}
+		ha2 := h(r.Method + ":" + r.URL.RequestURI())
+		want := h(strings.Join([]string{ha1, nonce, params["nc"], params["cnonce"], "auth", ha2}, ":"))
+		nc, _ := strconv.ParseInt(params["nc"], 16, 64)
+		if params["username"] != "admin" || params["uri"] != r.URL.RequestURI() ||
+			params["opaque"] != "opaque" || params["response"] != want || nc <= ds.lastNC {
+			ds.challenge(w, algorithms, false)
+			return
+		}
+
+		ds.lastNC = nc
+		ds.requests = append(ds.requests, params["algorithm"]+" "+params["nc"]+" "+string(b))
+		if len(ds.requests)%3 == 0 {
+			ds.nonce++
+		}
+	}))
+	return ds
+}
+
+func (ds *digestServer) challenge(w http.ResponseWriter, algorithms []string, stale bool) {
+	ds.challenges++
+	ds.lastNC = 0
+	for _, algorithm := range algorithms {
+		w.Header().Add("WWW-Authenticate", fmt.Sprintf(`Digest realm="sreq", qop="auth,auth-int", `+
+			`algorithm=%s, nonce="nonce%d", opaque="opaque", stale=%t`, algorithm, ds.nonce, stale))
+	}
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func TestDigestAuth(t *testing.T) {
+	ds := newDigestServer("MD5", "SHA-256")
+	defer ds.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewDigestAuth("admin", "pass").Middleware)
+
+	for i := 0; i < 4; i++ {
+		_, err := req.
+			Post(ds.URL+"/path?key=value",
+				sreq.WithText(fmt.Sprintf("body%d", i)),
+			).
+			EnsureStatusOk().
+			Resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"SHA-256 00000001 body0",
+		"SHA-256 00000002 body1",
+		"SHA-256 00000003 body2",
+		"SHA-256 00000001 body3",
+	}
+	if strings.Join(ds.requests, "|") != strings.Join(want, "|") {
+		t.Errorf("DigestAuth requests got: %v, want: %v", ds.requests, want)
+	}
+	// The first request and the stale nonce.
+	if ds.challenges != 2 {
+		t.Errorf("DigestAuth challenges got: %d, want: 2", ds.challenges)
+	}
+}
+
+func TestDigestAuth_NonceRotated(t *testing.T) {
+	ds := newDigestServer("MD5")
+	ds.silent = true
+	defer ds.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewDigestAuth("admin", "pass").Middleware)
+	for i := 0; i < 4; i++ {
+		_, err := req.Get(ds.URL).EnsureStatusOk().Resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ds.requests) != 4 || ds.challenges != 2 {
+		t.Errorf("DigestAuth got requests: %v, challenges: %d", ds.requests, ds.challenges)
+	}
+}
+
+func TestDigestAuth_Sess(t *testing.T) {
+	ds := newDigestServer("MD5-sess")
+	defer ds.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewDigestAuth("admin", "pass").Middleware)
+	_, err := req.Get(ds.URL).EnsureStatusOk().Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ds.requests) != 1 || ds.requests[0] != "MD5-sess 00000001 " {
+		t.Errorf("DigestAuth requests got: %v", ds.requests)
+	}
+}
+
+func TestDigestAuth_WrongPassword(t *testing.T) {
+	ds := newDigestServer("MD5")
+	defer ds.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewDigestAuth("admin", "wrong").Middleware)
+	for i := 0; i < 2; i++ {
+		resp := req.Get(ds.URL)
+		if resp.Err != nil {
+			t.Fatal(resp.Err)
+		}
+		if resp.R.StatusCode != http.StatusUnauthorized {
+			t.Errorf("DigestAuth status code got: %d, want: %d", resp.R.StatusCode, http.StatusUnauthorized)
+		}
+	}
+	// The challenge is answered once for the first request, and the cached one is not retried.
+	if ds.challenges != 3 {
+		t.Errorf("DigestAuth challenges got: %d, want: 3", ds.challenges)
+	}
+}
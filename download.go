@@ -0,0 +1,229 @@
+package sreq
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type (
+	checksum struct {
+		newHash func() hash.Hash
+		sum     string
+	}
+
+	// downloadState records the information for resuming an interrupted download.
+	downloadState struct {
+		URL       string `json:"url"`
+		Validator string `json:"validator"`
+	}
+)
+
+// errResumeFailed reports the server did not resume the download as expected.
+var errResumeFailed = errors.New("sreq: resume download failed")
+
+// WithChecksum specifies the expected checksum of the file that Download saves,
+// newHash creates the hash, like sha256.New, and sum is the expected hex-encoded digest.
+func WithChecksum(newHash func() hash.Hash, sum string) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		return withSettings(hr, func(s *requestSettings) {
+			s.checksum = &checksum{
+				newHash: newHash,
+				sum:     strings.ToLower(sum),
+			}
+		}), nil
+	}
+}
+
+// Download downloads the resource of the given URL into a file.
+func Download(url string, filename string, opts ...RequestOption) error {
+	return std.Download(url, filename, opts...)
+}
+
+// Download downloads the resource of the given URL into a file.
+// The data is written into a temporary ".part" file which is renamed to filename
+// once it completes, an interrupted download would be resumed by the next call
+// if the server supports range requests.
+func (c *Client) Download(url string, filename string, opts ...RequestOption) error {
+	err := c.download(url, filename, true, opts...)
+	if err == errResumeFailed {
+		err = c.download(url, filename, false, opts...)
+	}
+	return err
+}
+
+func (c *Client) download(url string, filename string, resume bool, opts ...RequestOption) error {
+	partName := filename + ".part"
+	stateName := partName + ".json"
+
+	var offset int64
+	state := loadDownloadState(stateName)
+	if resume && state != nil && state.URL == url && state.Validator != "" {
+		if fi, err := os.Stat(partName); err == nil && fi.Mode().IsRegular() {
+			offset = fi.Size()
+		}
+	}
+
+	var cs *checksum
+	opts = append(opts, func(hr *http.Request) (*http.Request, error) {
+		cs = settingsOf(hr).checksum
+		if offset > 0 {
+			hr.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			hr.Header.Set("If-Range", state.Validator)
+		}
+		return hr, nil
+	})
+
+	resp := c.Get(url, opts...)
+	if resp.Err != nil {
+		return resp.Err
+	}
+	defer resp.R.Body.Close()
+
+	total := resp.R.ContentLength
+	switch resp.R.StatusCode {
+	case http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.R.Header.Get("Content-Range"))
+		if offset == 0 || !ok || start != offset {
+			return errResumeFailed
+		}
+		total = size
+		if total < 0 && resp.R.ContentLength >= 0 {
+			total = offset + resp.R.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset > 0 {
+			return errResumeFailed
+		}
+		return resp.EnsureStatusOk().Err
+	case http.StatusOK:
+		offset = 0
+	default:
+		return resp.EnsureStatusOk().Err
+	}
+
+	if offset == 0 {
+		if err := saveDownloadState(stateName, &downloadState{
+			URL:       url,
+			Validator: validator(resp.R.Header),
+		}); err != nil {
+			return err
+		}
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flag = os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(partName, flag, 0664)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(file, resp.R.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if total >= 0 && offset+n != total {
+		return fmt.Errorf("sreq: incomplete download: got %d bytes, want %d", offset+n, total)
+	}
+
+	if cs != nil {
+		if err = verifyChecksum(partName, cs); err != nil {
+			os.Remove(partName)
+			os.Remove(stateName)
+			return err
+		}
+	}
+
+	if err = os.Rename(partName, filename); err != nil {
+		return err
+	}
+	os.Remove(stateName)
+	return nil
+}
+
+// validator returns a strong validator of the HTTP response for If-Range.
+func validator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// parseContentRange parses a Content-Range header like "bytes 100-199/200",
+// size is -1 if the complete length is unknown.
+func parseContentRange(v string) (start int64, size int64, ok bool) {
+	if !strings.HasPrefix(v, "bytes ") {
+		return 0, 0, false
+	}
+	v = strings.TrimPrefix(v, "bytes ")
+
+	i := strings.Index(v, "-")
+	j := strings.Index(v, "/")
+	if i < 0 || j < i {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(v[:i], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if v[j+1:] == "*" {
+		return start, -1, true
+	}
+	size, err = strconv.ParseInt(v[j+1:], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+func loadDownloadState(filename string) *downloadState {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+
+	state := new(downloadState)
+	if json.Unmarshal(b, state) != nil {
+		return nil
+	}
+	return state
+}
+
+func saveDownloadState(filename string, state *downloadState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0664)
+}
+
+func verifyChecksum(filename string, cs *checksum) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	h := cs.newHash()
+	if _, err = io.Copy(h, file); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != cs.sum {
+		return fmt.Errorf("sreq: checksum mismatch: got %s, want %s", sum, cs.sum)
+	}
+	return nil
+}
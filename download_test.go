@@ -0,0 +1,109 @@
+package sreq_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	var (
+		attempts int32
+		ranges   []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			// Interrupt the first download halfway.
+			w.Header().Set("Content-Length", "100000")
+			w.Write(content[:40000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "data.bin")
+	req := sreq.New(nil)
+	if err = req.Download(ts.URL, filename); err == nil {
+		t.Fatal("Interrupted download unchecked")
+	}
+	if _, err = os.Stat(filename); !os.IsNotExist(err) {
+		t.Error("Incomplete download not kept in a temporary file")
+	}
+
+	sum := sha256.Sum256(content)
+	err = req.Download(ts.URL, filename,
+		sreq.WithChecksum(sha256.New, hex.EncodeToString(sum[:])),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes=40000-" {
+		t.Errorf("Download resume got ranges: %q", ranges)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Error("Download got corrupted content")
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Download left temporary files: %d", len(files))
+	}
+
+	err = req.Download(ts.URL, filename,
+		sreq.WithChecksum(sha256.New, "0000"),
+	)
+	if err == nil {
+		t.Error("Checksum mismatch unchecked")
+	}
+
+	err = req.Download(ts.URL, filename,
+		sreq.WithChecksum(sha256.New, "0000"),
+		sreq.WithContext(context.Background()),
+	)
+	if err == nil {
+		t.Error("Checksum mismatch with WithContext unchecked")
+	}
+}
+
+func TestDownload_BadStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = sreq.New(nil).Download(ts.URL, filepath.Join(dir, "data.bin"))
+	if _, ok := err.(*sreq.HTTPError); !ok {
+		t.Errorf("Download got error: %v, want: *sreq.HTTPError", err)
+	}
+}
@@ -24,6 +24,7 @@ func main() {
 	// setDefaultRequestOpts()
 	// customizeHTTPClient()
 	// concurrentSafe()
+	// rateLimit()
 }
 
 func setQueryParams() {
@@ -226,3 +227,42 @@ func concurrentSafe() {
 
 	wg.Wait()
 }
+
+func rateLimit() {
+	const MaxWorker = 1000
+	wg := new(sync.WaitGroup)
+
+	limiter := sreq.NewRateLimiter(
+		sreq.Limit{},
+		sreq.Limit{
+			Rate:        10,
+			Burst:       10,
+			Concurrency: 5,
+		},
+	)
+	req := sreq.New(nil)
+	req.Use(limiter.Middleware)
+
+	for i := 0; i < MaxWorker; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			params := sreq.Params{}
+			params.Set(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i))
+
+			data, err := req.
+				Get("http://httpbin.org/get",
+					sreq.WithQuery(params),
+				).
+				Text()
+			if err != nil {
+				return
+			}
+
+			fmt.Println(data)
+		}(i)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,65 @@
+package sreq
+
+import (
+	"net/http"
+)
+
+type (
+	// Doer sends an HTTP request and returns its HTTP response, *http.Client implements it.
+	Doer interface {
+		Do(httpReq *http.Request) (*http.Response, error)
+	}
+
+	// DoerFunc is an adapter to allow the use of ordinary functions as Doer.
+	DoerFunc func(httpReq *http.Request) (*http.Response, error)
+
+	// Middleware wraps a Doer to intercept the round trip of per HTTP request,
+	// like logging, metrics, caching, etc.
+	Middleware func(next Doer) Doer
+)
+
+// Do calls f(httpReq).
+func (f DoerFunc) Do(httpReq *http.Request) (*http.Response, error) {
+	return f(httpReq)
+}
+
+// Use appends middlewares for per HTTP request.
+// The middlewares run in the order they are added, the first one is the outermost.
+func Use(middlewares ...Middleware) {
+	std.Use(middlewares...)
+}
+
+// Use appends middlewares for per HTTP request.
+// The middlewares run in the order they are added, the first one is the outermost.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.mux.Lock()
+	c.Middlewares = append(c.Middlewares, middlewares...)
+	c.mux.Unlock()
+}
+
+// ClearMiddlewares clears middlewares for per HTTP request.
+func ClearMiddlewares() {
+	std.ClearMiddlewares()
+}
+
+// ClearMiddlewares clears middlewares for per HTTP request.
+func (c *Client) ClearMiddlewares() {
+	c.mux.Lock()
+	c.Middlewares = nil
+	c.mux.Unlock()
+}
+
+// doer chains the middlewares of c around its HTTP client.
+func (c *Client) doer() Doer {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	hc := c.C
+	var d Doer = DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+		return clientFor(hc, httpReq).Do(httpReq)
+	})
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		d = c.Middlewares[i](d)
+	}
+	return d
+}
@@ -0,0 +1,77 @@
+package sreq_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestClient_Use(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Trace", r.Header.Get("X-Trace"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	var trace []string
+	layer := func(name string) sreq.Middleware {
+		return func(next sreq.Doer) sreq.Doer {
+			return sreq.DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+				trace = append(trace, name+" before")
+				httpReq.Header.Add("X-Trace", name)
+				httpResp, err := next.Do(httpReq)
+				if err == nil {
+					trace = append(trace, name+" after "+httpResp.Header.Get("X-Trace"))
+				}
+				return httpResp, err
+			})
+		}
+	}
+
+	req := sreq.New(nil)
+	req.Use(layer("m1"), layer("m2"))
+	_, err := req.
+		Get(ts.URL).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"m1 before", "m2 before", "m2 after m1", "m1 after m1"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("Client_Use got: %v, want: %v", trace, want)
+	}
+
+	req.ClearMiddlewares()
+	trace = nil
+	_, err = req.
+		Get(ts.URL).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace) != 0 {
+		t.Error("Client_ClearMiddlewares test failed")
+	}
+}
+
+func TestMiddleware_ShortCircuit(t *testing.T) {
+	errDenied := errors.New("denied")
+	req := sreq.New(nil)
+	req.Use(func(next sreq.Doer) sreq.Doer {
+		return sreq.DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+			return nil, errDenied
+		})
+	})
+
+	_, err := req.Get("http://127.0.0.1:0").Resolve()
+	if err == nil || !errors.Is(err, errDenied) {
+		t.Errorf("Middleware short circuit got: %v, want: %v", err, errDenied)
+	}
+}
@@ -0,0 +1,326 @@
+package sreq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type (
+	// FormFile specifies a file part of a multipart payload.
+	FormFile struct {
+		// FieldName specifies the form field name of the file.
+		FieldName string
+
+		// FileName specifies the file name of the part.
+		FileName string
+
+		// ContentType specifies the content type of the part, "application/octet-stream" by default.
+		ContentType string
+
+		// Open returns a reader of the file content.
+		// It may be called more than once if the HTTP request needs to be replayed.
+		Open func() (io.ReadCloser, error)
+
+		filePath string
+		size     int64
+		sized    bool
+		oneShot  bool
+	}
+
+	// Multipart is a builder of multipart/form-data payload,
+	// it can mix plain text fields and files from paths, readers or bytes.
+	Multipart struct {
+		parts []*multipartPart
+	}
+
+	multipartPart struct {
+		fieldName string
+		value     string
+		file      *FormFile
+	}
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// FileFromPath returns a form file which reads its content from the given file path.
+func FileFromPath(fieldName string, filePath string) *FormFile {
+	return &FormFile{
+		FieldName: fieldName,
+		FileName:  filepath.Base(filePath),
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		},
+		filePath: filePath,
+	}
+}
+
+// FileFromReader returns a form file which streams its content from r.
+func FileFromReader(fieldName string, fileName string, r io.Reader) *FormFile {
+	return &FormFile{
+		FieldName: fieldName,
+		FileName:  fileName,
+		Open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(r), nil
+		},
+		oneShot: true,
+	}
+}
+
+// FileFromBytes returns a form file which reads its content from b.
+func FileFromBytes(fieldName string, fileName string, b []byte) *FormFile {
+	return &FormFile{
+		FieldName: fieldName,
+		FileName:  fileName,
+		Open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		},
+		size:  int64(len(b)),
+		sized: true,
+	}
+}
+
+// NewMultipart returns an empty multipart payload builder.
+func NewMultipart() *Multipart {
+	return new(Multipart)
+}
+
+// AddField appends a plain text field to m.
+func (m *Multipart) AddField(fieldName string, value string) *Multipart {
+	m.parts = append(m.parts, &multipartPart{
+		fieldName: fieldName,
+		value:     value,
+	})
+	return m
+}
+
+// AddFile appends files to m, files can share the same field name.
+func (m *Multipart) AddFile(files ...*FormFile) *Multipart {
+	for _, file := range files {
+		m.parts = append(m.parts, &multipartPart{
+			fieldName: file.FieldName,
+			file:      file,
+		})
+	}
+	return m
+}
+
+// snapshot checks the files of m and returns a copy of it,
+// so that later changes of m won't affect the HTTP request.
+func (m *Multipart) snapshot() (*Multipart, error) {
+	mp := &Multipart{
+		parts: make([]*multipartPart, 0, len(m.parts)),
+	}
+	for _, part := range m.parts {
+		if part.file == nil {
+			mp.parts = append(mp.parts, part)
+			continue
+		}
+
+		file := *part.file
+		if file.Open == nil {
+			return nil, fmt.Errorf("sreq: file for %q has no content", part.fieldName)
+		}
+		if file.filePath != "" {
+			if _, err := ExistsFile(file.filePath); err != nil {
+				return nil, fmt.Errorf("sreq: file for %q not ready: %v", part.fieldName, err)
+			}
+			fi, err := os.Stat(file.filePath)
+			if err != nil {
+				return nil, fmt.Errorf("sreq: file for %q not ready: %v", part.fieldName, err)
+			}
+			file.size, file.sized = fi.Size(), true
+		}
+		mp.parts = append(mp.parts, &multipartPart{
+			fieldName: part.fieldName,
+			file:      &file,
+		})
+	}
+	return mp, nil
+}
+
+// replayable reports whether the content of m can be read more than once.
+func (m *Multipart) replayable() bool {
+	for _, part := range m.parts {
+		if part.file != nil && part.file.oneShot {
+			return false
+		}
+	}
+	return true
+}
+
+// contentLength computes the encoded length of m with the given boundary,
+// it reports false if the size of any file is unknown.
+func (m *Multipart) contentLength(boundary string) (int64, bool) {
+	cw := new(countWriter)
+	mw := multipart.NewWriter(cw)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return 0, false
+	}
+
+	var size int64
+	for _, part := range m.parts {
+		if part.file == nil {
+			if err := mw.WriteField(part.fieldName, part.value); err != nil {
+				return 0, false
+			}
+			continue
+		}
+
+		if !part.file.sized {
+			return 0, false
+		}
+		if _, err := mw.CreatePart(part.file.header()); err != nil {
+			return 0, false
+		}
+		size += part.file.size
+	}
+	if err := mw.Close(); err != nil {
+		return 0, false
+	}
+	return cw.n + size, true
+}
+
+// reader streams the content of m through a pipe, any error occurred would be
+// passed to the reading side, i.e. the HTTP client.
+// The writing goroutine starts on the first read, so a payload that is never
+// sent, e.g. used by Curl, doesn't leak it.
+func (m *Multipart) reader(boundary string) io.ReadCloser {
+	return &multipartReader{m: m, boundary: boundary}
+}
+
+type multipartReader struct {
+	m        *Multipart
+	boundary string
+
+	mux    sync.Mutex
+	pr     *io.PipeReader
+	closed bool
+}
+
+func (mr *multipartReader) pipe() (*io.PipeReader, error) {
+	mr.mux.Lock()
+	defer mr.mux.Unlock()
+
+	if mr.closed {
+		return nil, io.ErrClosedPipe
+	}
+	if mr.pr == nil {
+		r, w := io.Pipe()
+		mw := multipart.NewWriter(w)
+		mw.SetBoundary(mr.boundary)
+		go func() {
+			w.CloseWithError(mr.m.writeTo(mw))
+		}()
+		mr.pr = r
+	}
+	return mr.pr, nil
+}
+
+func (mr *multipartReader) Read(p []byte) (int, error) {
+	r, err := mr.pipe()
+	if err != nil {
+		return 0, err
+	}
+	return r.Read(p)
+}
+
+func (mr *multipartReader) Close() error {
+	mr.mux.Lock()
+	defer mr.mux.Unlock()
+
+	mr.closed = true
+	if mr.pr != nil {
+		return mr.pr.Close()
+	}
+	return nil
+}
+
+func (m *Multipart) writeTo(mw *multipart.Writer) error {
+	for _, part := range m.parts {
+		if part.file == nil {
+			if err := mw.WriteField(part.fieldName, part.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := part.file.writeTo(mw); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func (f *FormFile) header() textproto.MIMEHeader {
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(f.FieldName), quoteEscaper.Replace(f.FileName)))
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+func (f *FormFile) writeTo(mw *multipart.Writer) error {
+	w, err := mw.CreatePart(f.header())
+	if err != nil {
+		return err
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// WithMultipart sets multipart payload of the HTTP request.
+func WithMultipart(m *Multipart) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		mp, err := m.snapshot()
+		if err != nil {
+			return nil, err
+		}
+
+		boundary := multipart.NewWriter(nil).Boundary()
+		hr.Body = mp.reader(boundary)
+		hr.ContentLength = 0
+		hr.GetBody = nil
+		if n, ok := mp.contentLength(boundary); ok {
+			hr.ContentLength = n
+		}
+		if mp.replayable() {
+			hr.GetBody = func() (io.ReadCloser, error) {
+				return mp.reader(boundary), nil
+			}
+		}
+
+		hr.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+		return hr, nil
+	}
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	cw.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,239 @@
+package sreq_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+type multipartResponse struct {
+	Fields map[string][]string `json:"fields"`
+	Files  map[string][]struct {
+		FileName    string `json:"filename"`
+		ContentType string `json:"contentType"`
+		Content     string `json:"content"`
+	} `json:"files"`
+}
+
+func multipartServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp := make(map[string]interface{})
+		resp["fields"] = r.MultipartForm.Value
+		files := make(map[string][]map[string]string)
+		for fieldName, headers := range r.MultipartForm.File {
+			for _, fh := range headers {
+				file, _ := fh.Open()
+				b, _ := ioutil.ReadAll(file)
+				file.Close()
+				files[fieldName] = append(files[fieldName], map[string]string{
+					"filename":    fh.Filename,
+					"contentType": fh.Header.Get("Content-Type"),
+					"content":     string(b),
+				})
+			}
+		}
+		resp["files"] = files
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestWithMultipart(t *testing.T) {
+	ts := multipartServer()
+	defer ts.Close()
+
+	generated := sreq.FileFromReader("attachment", "report.csv", strings.NewReader("id,name\n1,sreq\n"))
+	generated.ContentType = "text/csv"
+	mp := sreq.NewMultipart().
+		AddField("title", "reports").
+		AddField("tag", "a").
+		AddField("tag", "b").
+		AddFile(
+			generated,
+			sreq.FileFromBytes("attachment", "hello.txt", []byte("hello world")),
+			sreq.FileFromPath("doc", "./testdata/testfile1.txt"),
+		)
+
+	resp := new(multipartResponse)
+	err := sreq.
+		Post(ts.URL,
+			sreq.WithMultipart(mp),
+		).
+		EnsureStatusOk().
+		JSON(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Fields["title"][0] != "reports" || len(resp.Fields["tag"]) != 2 {
+		t.Errorf("Multipart fields got: %v", resp.Fields)
+	}
+	attachments := resp.Files["attachment"]
+	if len(attachments) != 2 {
+		t.Fatalf("Multipart files got: %v", resp.Files)
+	}
+	if attachments[0].FileName != "report.csv" || attachments[0].ContentType != "text/csv" ||
+		attachments[0].Content != "id,name\n1,sreq\n" {
+		t.Errorf("Multipart file from reader got: %+v", attachments[0])
+	}
+	if attachments[1].FileName != "hello.txt" || attachments[1].ContentType != "application/octet-stream" ||
+		attachments[1].Content != "hello world" {
+		t.Errorf("Multipart file from bytes got: %+v", attachments[1])
+	}
+	if docs := resp.Files["doc"]; len(docs) != 1 || docs[0].FileName != "testfile1.txt" {
+		t.Errorf("Multipart file from path got: %v", resp.Files["doc"])
+	}
+}
+
+func TestWithMultipart_Error(t *testing.T) {
+	ts := multipartServer()
+	defer ts.Close()
+
+	_, err := sreq.
+		Post(ts.URL,
+			sreq.WithMultipart(sreq.NewMultipart().AddFile(
+				sreq.FileFromPath("file", "./testdata/file_does_not_exist.txt"),
+			)),
+		).
+		Resolve()
+	if err == nil {
+		t.Error("Nonexistent file unchecked")
+	}
+
+	errBroken := errors.New("broken reader")
+	broken := &sreq.FormFile{
+		FieldName: "file",
+		FileName:  "broken.txt",
+		Open: func() (io.ReadCloser, error) {
+			return nil, errBroken
+		},
+	}
+	_, err = sreq.
+		Post(ts.URL,
+			sreq.WithMultipart(sreq.NewMultipart().AddFile(broken)),
+		).
+		Resolve()
+	if err == nil || !errors.Is(err, errBroken) {
+		t.Errorf("Multipart write error got: %v, want: %v", err, errBroken)
+	}
+}
+
+func TestWithFiles_Replayable(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= 0 || len(r.TransferEncoding) != 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(r.MultipartForm.File["file1"]) != 1 || len(r.MultipartForm.File["file2"]) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	_, err := sreq.
+		Post(ts.URL,
+			sreq.WithFiles(sreq.Files{
+				"file1": "./testdata/testfile1.txt",
+				"file2": "./testdata/testfile2.txt",
+			}),
+			sreq.WithRetry(&sreq.RetryPolicy{
+				MaxAttempts:        2,
+				RetryNonIdempotent: true,
+			}),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("Replay files got attempts: %d, want: %d", attempts, 2)
+	}
+}
+
+func TestWithMultipart_ContentLength(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.ContentLength >= 0 && r.ContentLength != int64(len(b)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, r.ContentLength)
+	}))
+	defer ts.Close()
+
+	data, err := sreq.
+		Post(ts.URL,
+			sreq.WithMultipart(sreq.NewMultipart().
+				AddField("key", "value").
+				AddFile(sreq.FileFromBytes("file", "hello.txt", []byte("hello world"))),
+			),
+		).
+		EnsureStatusOk().
+		Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data == "-1" {
+		t.Error("Multipart content length not computed")
+	}
+
+	data, err = sreq.
+		Post(ts.URL,
+			sreq.WithMultipart(sreq.NewMultipart().
+				AddFile(sreq.FileFromReader("file", "hello.txt", strings.NewReader("hello world"))),
+			),
+		).
+		EnsureStatusOk().
+		Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "-1" {
+		t.Errorf("Multipart content length of reader got: %s, want: -1", data)
+	}
+}
+
+func TestWithMultipart_Lazy(t *testing.T) {
+	mp := sreq.NewMultipart().
+		AddField("key", "value").
+		AddFile(sreq.FileFromBytes("file", "hello.txt", []byte("hello world")))
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		if _, err := sreq.Curl(sreq.MethodPost, "http://httpbin.org/post", sreq.WithMultipart(mp)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sreq.NewRequest(sreq.MethodPost, "http://httpbin.org/post", sreq.WithMultipart(mp)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := runtime.NumGoroutine(); after > before+5 {
+		t.Errorf("WithMultipart leaked goroutines, before: %d, after: %d", before, after)
+	}
+}
@@ -0,0 +1,213 @@
+package sreq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultExpiryDelta is how long before the expiry an OAuth2 token is refreshed.
+const defaultExpiryDelta = 10 * time.Second
+
+type (
+	// OAuth2Config specifies how to fetch OAuth2 access tokens.
+	OAuth2Config struct {
+		// TokenURL is the token endpoint of the authorization server.
+		TokenURL string
+
+		ClientID     string
+		ClientSecret string
+
+		// Scopes specifies the requested scopes of the client-credentials grant.
+		Scopes []string
+
+		// RefreshToken, if set, makes the token source use the refresh-token grant,
+		// otherwise the client-credentials grant is used.
+		RefreshToken string
+
+		// Params specifies the extra params of the token requests, like "audience".
+		Params Form
+
+		// AuthInBody makes the client credentials sent in the request body instead of
+		// the HTTP Basic authentication.
+		AuthInBody bool
+
+		// ExpiryDelta specifies how long before the expiry a token is refreshed, 10s if not positive.
+		ExpiryDelta time.Duration
+
+		// Client is used to request the token endpoint, a new client is used if nil.
+		// It must not use the middleware of the token source.
+		Client *Client
+	}
+
+	// OAuth2Token represents an OAuth2 access token.
+	OAuth2Token struct {
+		AccessToken  string    `json:"access_token"`
+		TokenType    string    `json:"token_type,omitempty"`
+		RefreshToken string    `json:"refresh_token,omitempty"`
+		ExpiresIn    int64     `json:"expires_in,omitempty"`
+		Expiry       time.Time `json:"-"`
+	}
+
+	// OAuth2TokenSource fetches OAuth2 access tokens and caches them until shortly before expiry,
+	// it's concurrent safe. Attach it to a client with Client.Use(ts.Middleware).
+	OAuth2TokenSource struct {
+		config       OAuth2Config
+		client       *Client
+		token        *OAuth2Token
+		refreshToken string
+		mux          sync.Mutex
+	}
+)
+
+// NewOAuth2TokenSource returns a token source of the given config.
+func NewOAuth2TokenSource(config *OAuth2Config) *OAuth2TokenSource {
+	ts := &OAuth2TokenSource{
+		config:       *config,
+		client:       config.Client,
+		refreshToken: config.RefreshToken,
+	}
+	if ts.client == nil {
+		ts.client = New(nil)
+	}
+	if ts.config.ExpiryDelta <= 0 {
+		ts.config.ExpiryDelta = defaultExpiryDelta
+	}
+	return ts
+}
+
+// Type returns the token type used in the Authorization header, "Bearer" by default.
+func (t *OAuth2Token) Type() string {
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, "bearer") {
+		return "Bearer"
+	}
+	return t.TokenType
+}
+
+// Token returns a valid token, it's fetched from the token endpoint if the cached one
+// is missing or about to expire. Concurrent callers share a single fetch.
+func (ts *OAuth2TokenSource) Token(ctx context.Context) (*OAuth2Token, error) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	if ts.token != nil && (ts.token.Expiry.IsZero() || time.Now().Add(ts.config.ExpiryDelta).Before(ts.token.Expiry)) {
+		return ts.token, nil
+	}
+
+	var (
+		token *OAuth2Token
+		err   error
+	)
+	if ts.refreshToken != "" {
+		token, err = ts.fetch(ctx, Form{
+			"grant_type":    "refresh_token",
+			"refresh_token": ts.refreshToken,
+		})
+		if err != nil && ts.config.RefreshToken == "" {
+			// The refresh token was issued along with a client-credentials token, start over.
+			token, err = ts.fetchClientCredentials(ctx)
+		}
+	} else {
+		token, err = ts.fetchClientCredentials(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if token.RefreshToken != "" {
+		ts.refreshToken = token.RefreshToken
+	}
+	ts.token = token
+	return token, nil
+}
+
+// invalidate drops the cached token if it's still the given one, e.g. it's rejected by the server.
+func (ts *OAuth2TokenSource) invalidate(token *OAuth2Token) {
+	ts.mux.Lock()
+	if ts.token == token {
+		ts.token = nil
+	}
+	ts.mux.Unlock()
+}
+
+func (ts *OAuth2TokenSource) fetchClientCredentials(ctx context.Context) (*OAuth2Token, error) {
+	form := Form{
+		"grant_type": "client_credentials",
+	}
+	if len(ts.config.Scopes) != 0 {
+		form.Set("scope", strings.Join(ts.config.Scopes, " "))
+	}
+	return ts.fetch(ctx, form)
+}
+
+func (ts *OAuth2TokenSource) fetch(ctx context.Context, form Form) (*OAuth2Token, error) {
+	for k, v := range ts.config.Params {
+		form[k] = v
+	}
+
+	// The settings of sreq in ctx, e.g. set by WithTimeout or WithRetry, are meant for the request
+	// being authorized, not for the token request.
+	opts := []RequestOption{WithContext(withoutSettings(ctx))}
+	if ts.config.AuthInBody {
+		form.Set("client_id", ts.config.ClientID)
+		if ts.config.ClientSecret != "" {
+			form.Set("client_secret", ts.config.ClientSecret)
+		}
+	} else if ts.config.ClientID != "" {
+		opts = append(opts, WithBasicAuth(ts.config.ClientID, ts.config.ClientSecret))
+	}
+	opts = append(opts, WithForm(form))
+
+	token := new(OAuth2Token)
+	err := ts.client.
+		Post(ts.config.TokenURL, opts...).
+		EnsureStatus2xx().
+		JSON(token)
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("sreq: oauth2: server response missing access_token")
+	}
+
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// Middleware authorizes the HTTP requests sent by next with the access token.
+// If the server responds 401, the token is refreshed and the request is retried once.
+func (ts *OAuth2TokenSource) Middleware(next Doer) Doer {
+	return DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+		token, err := ts.Token(httpReq.Context())
+		if err != nil {
+			return nil, err
+		}
+
+		httpResp, err := next.Do(authorize(httpReq, token))
+		if err != nil || httpResp.StatusCode != http.StatusUnauthorized {
+			return httpResp, err
+		}
+		if rewindBody(httpReq) != nil {
+			return httpResp, nil
+		}
+
+		drainBody(&Response{R: httpResp})
+		ts.invalidate(token)
+		token, err = ts.Token(httpReq.Context())
+		if err != nil {
+			return nil, err
+		}
+		return next.Do(authorize(httpReq, token))
+	})
+}
+
+func authorize(httpReq *http.Request, token *OAuth2Token) *http.Request {
+	httpReq = httpReq.Clone(httpReq.Context())
+	httpReq.Header.Set("Authorization", token.Type()+" "+token.AccessToken)
+	return httpReq
+}
@@ -0,0 +1,252 @@
+package sreq_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+type tokenServer struct {
+	*httptest.Server
+	issued    int32
+	expiresIn int64
+	grants    chan string
+}
+
+func newTokenServer(expiresIn int64) *tokenServer {
+	ts := &tokenServer{
+		expiresIn: expiresIn,
+		grants:    make(chan string, 100),
+	}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+		}
+		if clientID != "client" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		grant := r.PostFormValue("grant_type")
+		ts.grants <- grant
+		if grant == "refresh_token" && r.PostFormValue("refresh_token") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		n := atomic.AddInt32(&ts.issued, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("token%d", n),
+			"token_type":    "bearer",
+			"refresh_token": fmt.Sprintf("refresh%d", n),
+			"expires_in":    ts.expiresIn,
+		})
+	}))
+	return ts
+}
+
+func TestOAuth2TokenSource(t *testing.T) {
+	tokenSrv := newTokenServer(3600)
+	defer tokenSrv.Close()
+
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer apiSrv.Close()
+
+	ts := sreq.NewOAuth2TokenSource(&sreq.OAuth2Config{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	})
+	req := sreq.New(nil)
+	req.Use(ts.Middleware)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := req.Get(apiSrv.URL).EnsureStatusOk().Resolve(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if tokenSrv.issued != 1 {
+		t.Errorf("OAuth2 tokens issued: %d, want: 1", tokenSrv.issued)
+	}
+	if grant := <-tokenSrv.grants; grant != "client_credentials" {
+		t.Errorf("OAuth2 grant got: %s, want: client_credentials", grant)
+	}
+}
+
+func TestOAuth2TokenSource_Refresh(t *testing.T) {
+	// Tokens expiring within ExpiryDelta are refreshed for every request.
+	tokenSrv := newTokenServer(5)
+	defer tokenSrv.Close()
+
+	ts := sreq.NewOAuth2TokenSource(&sreq.OAuth2Config{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RefreshToken: "refresh0",
+		AuthInBody:   true,
+	})
+
+	for i := 1; i <= 3; i++ {
+		token, err := ts.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("token%d", i); token.AccessToken != want {
+			t.Errorf("OAuth2 token got: %s, want: %s", token.AccessToken, want)
+		}
+		if grant := <-tokenSrv.grants; grant != "refresh_token" {
+			t.Errorf("OAuth2 grant got: %s, want: refresh_token", grant)
+		}
+	}
+}
+
+func TestOAuth2TokenSource_Retry(t *testing.T) {
+	tokenSrv := newTokenServer(0)
+	defer tokenSrv.Close()
+
+	var attempts int32
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		b, _ := ioutil.ReadAll(r.Body)
+		// The first token is revoked.
+		if r.Header.Get("Authorization") != "Bearer token2" || string(b) != "hello world" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer apiSrv.Close()
+
+	ts := sreq.NewOAuth2TokenSource(&sreq.OAuth2Config{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	})
+	req := sreq.New(nil)
+	req.Use(ts.Middleware)
+
+	_, err := req.
+		Post(apiSrv.URL,
+			sreq.WithText("hello world"),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("OAuth2 attempts got: %d, want: 2", attempts)
+	}
+	if grant := <-tokenSrv.grants; grant != "client_credentials" {
+		t.Errorf("OAuth2 first grant got: %s, want: client_credentials", grant)
+	}
+	if grant := <-tokenSrv.grants; grant != "refresh_token" {
+		t.Errorf("OAuth2 second grant got: %s, want: refresh_token", grant)
+	}
+
+	// Retry only once.
+	atomic.StoreInt32(&attempts, 0)
+	resp := req.Get(apiSrv.URL)
+	if resp.Err != nil {
+		t.Fatal(resp.Err)
+	}
+	if resp.R.StatusCode != http.StatusUnauthorized || attempts != 2 {
+		t.Errorf("OAuth2 retry got status: %d, attempts: %d", resp.R.StatusCode, attempts)
+	}
+}
+
+func TestOAuth2TokenSource_Timeout(t *testing.T) {
+	tokenSrv := newTokenServer(3600)
+	defer tokenSrv.Close()
+
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer apiSrv.Close()
+
+	ts := sreq.NewOAuth2TokenSource(&sreq.OAuth2Config{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	})
+	req := sreq.New(nil)
+	req.Use(ts.Middleware)
+
+	// The token request doesn't release the context of the authorized request,
+	// but keeps the other values of the context, like the client trace.
+	var conns int32
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			atomic.AddInt32(&conns, 1)
+		},
+	})
+	data, err := req.
+		Get(apiSrv.URL,
+			sreq.WithTimeout(5*time.Second),
+			sreq.WithContext(ctx),
+		).
+		EnsureStatusOk().
+		Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "Bearer token1" {
+		t.Errorf("OAuth2 Authorization got: %s, want: Bearer token1", data)
+	}
+	if conns != 2 {
+		t.Errorf("OAuth2 client trace got connections: %d, want: 2", conns)
+	}
+
+	// But it's still canceled along with the authorized request.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ts = sreq.NewOAuth2TokenSource(&sreq.OAuth2Config{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+	})
+	if _, err = ts.Token(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("OAuth2 canceled token request got error: %v", err)
+	}
+}
+
+func TestOAuth2TokenSource_Error(t *testing.T) {
+	tokenSrv := newTokenServer(3600)
+	defer tokenSrv.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewOAuth2TokenSource(&sreq.OAuth2Config{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "client",
+		ClientSecret: "wrong",
+	}).Middleware)
+
+	_, err := req.Get("http://127.0.0.1:0").Resolve()
+	httpErr := new(sreq.HTTPError)
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("OAuth2 token error got: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package sreq
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+type (
+	// Progress reports the progress of a transfer.
+	Progress struct {
+		// Transferred specifies the bytes transferred so far.
+		Transferred int64
+
+		// Total specifies the total bytes to transfer, -1 if unknown.
+		Total int64
+
+		// Rate specifies the average transfer rate in bytes per second.
+		Rate float64
+	}
+
+	// ProgressFunc is called each time some bytes are transferred.
+	ProgressFunc func(p Progress)
+
+	progressReader struct {
+		io.ReadCloser
+		fn          ProgressFunc
+		total       int64
+		transferred int64
+		start       time.Time
+	}
+)
+
+func newProgressReader(rc io.ReadCloser, total int64, fn ProgressFunc) *progressReader {
+	if total <= 0 {
+		total = -1
+	}
+	return &progressReader{
+		ReadCloser: rc,
+		fn:         fn,
+		total:      total,
+		start:      time.Now(),
+	}
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.ReadCloser.Read(p)
+	if n > 0 {
+		pr.transferred += int64(n)
+		progress := Progress{
+			Transferred: pr.transferred,
+			Total:       pr.total,
+		}
+		if elapsed := time.Since(pr.start).Seconds(); elapsed > 0 {
+			progress.Rate = float64(pr.transferred) / elapsed
+		}
+		pr.fn(progress)
+	}
+	return n, err
+}
+
+// WithUploadProgress reports the upload progress of the HTTP request payload.
+// The progress is tracked on the payload being sent, after all the other options are applied.
+func WithUploadProgress(fn ProgressFunc) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		return deferOption(hr, stageUploadProgress, func(hr *http.Request) (*http.Request, error) {
+			if hr.Body == nil || hr.Body == http.NoBody {
+				return hr, nil
+			}
+
+			// Only the body being sent is tracked, not the copies from GetBody, e.g. read by Dumper.
+			hr.Body = newProgressReader(hr.Body, hr.ContentLength, fn)
+			return withSettings(hr, func(s *requestSettings) {
+				s.uploadProgress = fn
+			}), nil
+		}), nil
+	}
+}
+
+// WithDownloadProgress reports the download progress of the HTTP response body.
+func WithDownloadProgress(fn ProgressFunc) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		return withSettings(hr, func(s *requestSettings) {
+			s.downloadProgress = fn
+		}), nil
+	}
+}
+
+func trackDownloadProgress(httpReq *http.Request, httpResp *http.Response) {
+	if fn := settingsOf(httpReq).downloadProgress; fn != nil {
+		httpResp.Body = newProgressReader(httpResp.Body, httpResp.ContentLength, fn)
+	}
+}
+
+// SaveWithProgress saves the HTTP response into a file, and reports the download progress.
+func (r *Response) SaveWithProgress(filename string, fn ProgressFunc) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.buffered {
+		if err := r.Save(filename); err != nil {
+			return err
+		}
+
+		n := int64(len(r.body))
+		fn(Progress{
+			Transferred: n,
+			Total:       n,
+		})
+		return nil
+	}
+
+	r.R.Body = newProgressReader(r.R.Body, r.R.ContentLength, fn)
+	return r.Save(filename)
+}
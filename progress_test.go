@@ -0,0 +1,138 @@
+package sreq_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestWithUploadProgress(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	var last sreq.Progress
+	_, err := sreq.
+		Post(ts.URL,
+			sreq.WithFiles(sreq.Files{
+				"image1": "./testdata/testimage1.jpg",
+				"image2": "./testdata/testimage2.jpg",
+			}),
+			sreq.WithUploadProgress(func(p sreq.Progress) {
+				last = p
+			}),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Total <= 0 || last.Transferred != last.Total || last.Rate <= 0 {
+		t.Errorf("WithUploadProgress got: %+v", last)
+	}
+}
+
+func TestWithUploadProgress_Deferred(t *testing.T) {
+	var received int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		atomic.StoreInt64(&received, n)
+	}))
+	defer ts.Close()
+
+	var (
+		calls int
+		last  sreq.Progress
+	)
+	req := sreq.New(nil)
+	req.Debug(log.New(ioutil.Discard, "", 0))
+	httpReq, err := req.NewRequest(sreq.MethodPost, ts.URL,
+		sreq.WithUploadProgress(func(p sreq.Progress) {
+			if p.Transferred < last.Transferred || (p.Total > 0 && p.Transferred > p.Total) {
+				t.Errorf("WithUploadProgress reported twice: %+v after %+v", p, last)
+			}
+			calls++
+			last = p
+		}),
+		sreq.WithText(strings.Repeat("hello world", 1000)),
+		sreq.WithCompression(sreq.EncodingGzip, 0),
+		sreq.WithHMAC(&sreq.HMACSigner{
+			Key: []byte("secret"),
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("WithUploadProgress reported before sending: %+v", last)
+	}
+
+	if _, err = req.Send(httpReq).EnsureStatusOk().Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || last.Transferred != received || last.Total != received {
+		t.Errorf("WithUploadProgress got: %+v, want: %d bytes", last, received)
+	}
+}
+
+func TestWithDownloadProgress(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1<<20)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content)
+	}))
+	defer ts.Close()
+
+	var (
+		calls int
+		last  sreq.Progress
+	)
+	fn := func(p sreq.Progress) {
+		calls++
+		last = p
+	}
+
+	_, err := sreq.
+		Get(ts.URL,
+			sreq.WithDownloadProgress(fn),
+			sreq.WithContext(context.Background()),
+		).
+		Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || last.Transferred != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("WithDownloadProgress got: %+v", last)
+	}
+
+	file, err := ioutil.TempFile("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	last = sreq.Progress{}
+	err = sreq.
+		Get(ts.URL).
+		EnsureStatusOk().
+		SaveWithProgress(file.Name(), fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Transferred != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("Response_SaveWithProgress got: %+v", last)
+	}
+}
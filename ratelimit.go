@@ -0,0 +1,273 @@
+package sreq
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Limit specifies the rate and concurrency limit of HTTP requests.
+	Limit struct {
+		// Rate specifies the number of requests allowed per second, no limit if it's not positive.
+		Rate float64
+
+		// Burst specifies the max number of requests allowed to happen at once, 1 at least.
+		Burst int
+
+		// Concurrency specifies the max number of requests in flight, no limit if it's not positive.
+		// A request is in flight until its response body is fully read or closed, so the caller
+		// must read or close the body, e.g. by Raw, Text, JSON or Save, even if the response
+		// is resolved by Resolve or rejected by EnsureStatus. Responses of HEAD requests
+		// or with empty bodies are released at once.
+		Concurrency int
+	}
+
+	// RateLimiter limits HTTP requests globally and per host with token buckets,
+	// it's concurrent safe and can be shared by clients.
+	// Attach it to a client with Client.Use(limiter.Middleware).
+	RateLimiter struct {
+		global      *limiter
+		hostLimit   Limit
+		hostLimits  map[string]Limit
+		hostBuckets map[string]*limiter
+		sweepAt     int
+		mux         sync.Mutex
+	}
+
+	limiter struct {
+		limit  Limit
+		tokens float64
+		last   time.Time
+		sem    chan struct{}
+		mux    sync.Mutex
+
+		// users counts the waiting and in-flight requests of a host limiter, guarded by RateLimiter.mux.
+		users int
+	}
+)
+
+// minHostBucketsSweep is the number of host limiters from which the idle ones are removed.
+const minHostBucketsSweep = 64
+
+// NewRateLimiter returns a rate limiter which applies the global limit to all requests,
+// and the host limit to the requests of each host.
+func NewRateLimiter(global Limit, host Limit) *RateLimiter {
+	return &RateLimiter{
+		global:      newLimiter(global),
+		hostLimit:   host,
+		hostLimits:  make(map[string]Limit),
+		hostBuckets: make(map[string]*limiter),
+		sweepAt:     minHostBucketsSweep,
+	}
+}
+
+// SetHostLimit sets the limit of the given host, it overrides the default host limit.
+func (rl *RateLimiter) SetHostLimit(host string, limit Limit) {
+	host = strings.ToLower(host)
+
+	rl.mux.Lock()
+	rl.hostLimits[host] = limit
+	delete(rl.hostBuckets, host)
+	rl.mux.Unlock()
+}
+
+func (rl *RateLimiter) hostLimiter(host string) *limiter {
+	host = strings.ToLower(host)
+
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+
+	l, ok := rl.hostBuckets[host]
+	if !ok {
+		if len(rl.hostBuckets) >= rl.sweepAt {
+			rl.sweep()
+		}
+
+		limit, ok := rl.hostLimits[host]
+		if !ok {
+			limit = rl.hostLimit
+		}
+		l = newLimiter(limit)
+		rl.hostBuckets[host] = l
+	}
+	l.users++
+	return l
+}
+
+// done marks a request of the host limiter l as finished.
+func (rl *RateLimiter) done(l *limiter) {
+	rl.mux.Lock()
+	l.users--
+	rl.mux.Unlock()
+}
+
+// sweep removes the idle host limiters, which are the same as new ones,
+// so that the limiters of the hosts ever requested don't pile up. The caller must hold rl.mux.
+func (rl *RateLimiter) sweep() {
+	now := time.Now()
+	for host, l := range rl.hostBuckets {
+		if l.users == 0 && l.full(now) {
+			delete(rl.hostBuckets, host)
+		}
+	}
+
+	rl.sweepAt = 2 * len(rl.hostBuckets)
+	if rl.sweepAt < minHostBucketsSweep {
+		rl.sweepAt = minHostBucketsSweep
+	}
+}
+
+// Wait blocks until a request to the given host is allowed or ctx is done.
+// The caller must call release once the request is finished.
+func (rl *RateLimiter) Wait(ctx context.Context, host string) (release func(), err error) {
+	hl := rl.hostLimiter(host)
+	limiters := []*limiter{rl.global, hl}
+
+	var delay time.Duration
+	for _, l := range limiters {
+		if d := l.reserve(); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			for _, l := range limiters {
+				l.cancel()
+			}
+			rl.done(hl)
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	for i, l := range limiters {
+		if err = l.acquire(ctx); err != nil {
+			for _, acquired := range limiters[:i] {
+				acquired.release()
+			}
+			rl.done(hl)
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			for _, l := range limiters {
+				l.release()
+			}
+			rl.done(hl)
+		})
+	}, nil
+}
+
+// Middleware limits the HTTP requests sent by next.
+func (rl *RateLimiter) Middleware(next Doer) Doer {
+	return DoerFunc(func(httpReq *http.Request) (*http.Response, error) {
+		release, err := rl.Wait(httpReq.Context(), httpReq.URL.Hostname())
+		if err != nil {
+			return nil, err
+		}
+
+		httpResp, err := next.Do(httpReq)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		// Nothing would read or close the body of these responses.
+		if httpReq.Method == MethodHead || httpResp.ContentLength == 0 ||
+			httpResp.Body == nil || httpResp.Body == http.NoBody {
+			release()
+			return httpResp, nil
+		}
+		httpResp.Body = newHookBody(httpResp.Body, release)
+		return httpResp, nil
+	})
+}
+
+func newLimiter(limit Limit) *limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	l := &limiter{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+	if limit.Concurrency > 0 {
+		l.sem = make(chan struct{}, limit.Concurrency)
+	}
+	return l
+}
+
+// reserve takes a token and returns how long to wait before it's available.
+func (l *limiter) reserve() time.Duration {
+	if l.limit.Rate <= 0 {
+		return 0
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.limit.Rate
+	if burst := float64(l.limit.Burst); l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.limit.Rate * float64(time.Second))
+}
+
+// full reports whether the bucket of l is refilled at now.
+func (l *limiter) full(now time.Time) bool {
+	if l.limit.Rate <= 0 {
+		return true
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.tokens+now.Sub(l.last).Seconds()*l.limit.Rate >= float64(l.limit.Burst)
+}
+
+// cancel gives back a token taken by reserve.
+func (l *limiter) cancel() {
+	if l.limit.Rate <= 0 {
+		return
+	}
+
+	l.mux.Lock()
+	l.tokens++
+	l.mux.Unlock()
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	if l.sem == nil {
+		return nil
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release() {
+	if l.sem != nil {
+		<-l.sem
+	}
+}
@@ -0,0 +1,162 @@
+package sreq_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewRateLimiter(sreq.Limit{}, sreq.Limit{
+		Rate:  20,
+		Burst: 1,
+	}).Middleware)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := req.Get(ts.URL).EnsureStatusOk().Raw(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("RateLimiter got elapsed: %s, want at least: %s", elapsed, 200*time.Millisecond)
+	}
+}
+
+func TestRateLimiter_SetHostLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	limiter := sreq.NewRateLimiter(sreq.Limit{}, sreq.Limit{})
+	limiter.SetHostLimit(u.Hostname(), sreq.Limit{
+		Rate:  1,
+		Burst: 1,
+	})
+
+	req := sreq.New(nil)
+	req.Use(limiter.Middleware)
+	if _, err := req.Get(ts.URL).EnsureStatusOk().Raw(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err := req.
+		Get(ts.URL,
+			sreq.WithTimeout(50*time.Millisecond),
+		).
+		Resolve()
+	if err == nil {
+		t.Error("RateLimiter ignored context cancellation")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("RateLimiter waited too long after cancellation: %s", elapsed)
+	}
+}
+
+func TestRateLimiter_Concurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewRateLimiter(sreq.Limit{}, sreq.Limit{
+		Concurrency: 2,
+	}).Middleware)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := req.Get(ts.URL).EnsureStatusOk().Raw(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("RateLimiter got max in flight: %d, want: %d", maxInFlight, 2)
+	}
+}
+
+func TestRateLimiter_Release(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	req.Use(sreq.NewRateLimiter(sreq.Limit{}, sreq.Limit{
+		Concurrency: 1,
+	}).Middleware)
+
+	// The responses whose bodies are never read or closed don't hold the slot.
+	for i := 0; i < 2; i++ {
+		if _, err := req.Head(ts.URL, sreq.WithTimeout(time.Second)).EnsureStatusOk().Resolve(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := req.Get(ts.URL+"/empty", sreq.WithTimeout(time.Second)).Resolve(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRateLimiter_ManyHosts(t *testing.T) {
+	rl := sreq.NewRateLimiter(sreq.Limit{}, sreq.Limit{
+		Rate:        1,
+		Concurrency: 1,
+	})
+
+	release, err := rl.Wait(context.Background(), "busy.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	for i := 0; i < 200; i++ {
+		r, err := rl.Wait(context.Background(), fmt.Sprintf("host%d.example.com", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r()
+	}
+
+	// The limiter in use is kept.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = rl.Wait(ctx, "busy.example.com"); err == nil {
+		t.Error("RateLimiter lost the limiter in use")
+	}
+}
@@ -5,15 +5,13 @@ import (
 	"context"
 	"encoding/base64"
 	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	stdurl "net/url"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 const (
@@ -87,7 +85,7 @@ func Put(url string, opts ...RequestOption) *Response {
 
 // Put makes a PUT HTTP request.
 func (c *Client) Put(url string, opts ...RequestOption) *Response {
-	return std.Request(MethodPut, url, opts...)
+	return c.Request(MethodPut, url, opts...)
 }
 
 // Patch makes a PATCH HTTP request.
@@ -147,11 +145,33 @@ func Request(method string, url string, opts ...RequestOption) *Response {
 
 // Request makes an HTTP request using a specified method.
 func (c *Client) Request(method string, url string, opts ...RequestOption) *Response {
-	resp := new(Response)
+	httpReq, err := c.NewRequest(method, url, opts...)
+	if err != nil {
+		return &Response{
+			Err: err,
+		}
+	}
+
+	return c.Send(httpReq)
+}
+
+// NewRequest builds an HTTP request and applies the default and the given request options,
+// the returned request can be inspected, signed or modified before being sent by Send.
+func NewRequest(method string, url string, opts ...RequestOption) (*http.Request, error) {
+	return std.NewRequest(method, url, opts...)
+}
+
+// NewRequest builds an HTTP request and applies the default and the given request options,
+// the returned request can be inspected, signed or modified before being sent by Send.
+func (c *Client) NewRequest(method string, url string, opts ...RequestOption) (*http.Request, error) {
+	url, err := c.resolveURL(url)
+	if err != nil {
+		return nil, err
+	}
+
 	httpReq, err := http.NewRequest(method, url, nil)
 	if err != nil {
-		resp.Err = err
-		return resp
+		return nil, err
 	}
 
 	httpReq.Header.Set("User-Agent", "sreq "+Version)
@@ -161,8 +181,7 @@ func (c *Client) Request(method string, url string, opts ...RequestOption) *Resp
 		httpReq, err = opt(httpReq)
 		if err != nil {
 			c.mux.RUnlock()
-			resp.Err = err
-			return resp
+			return nil, err
 		}
 	}
 	c.mux.RUnlock()
@@ -170,12 +189,94 @@ func (c *Client) Request(method string, url string, opts ...RequestOption) *Resp
 	for _, opt := range opts {
 		httpReq, err = opt(httpReq)
 		if err != nil {
-			resp.Err = err
-			return resp
+			return nil, err
 		}
 	}
 
-	return c.Send(httpReq)
+	return applyDeferred(httpReq)
+}
+
+type (
+	// requestSettings holds the per-request settings of sreq, like the retry policy.
+	// They're kept in the request context as a whole, so that WithContext can carry them over.
+	// A requestSettings is never modified once it's stored, use withSettings to update it.
+	requestSettings struct {
+		retryPolicy      *RetryPolicy
+		hasRetryPolicy   bool
+		maxBodySize      int64
+		checksum         *checksum
+		uploadProgress   ProgressFunc
+		downloadProgress ProgressFunc
+		timeout          time.Duration
+		timeoutCancel    context.CancelFunc
+		deferred         []deferredOption
+	}
+
+	requestSettingsKey struct{}
+
+	deferredOption struct {
+		stage int
+		opt   RequestOption
+	}
+)
+
+// Stages of the deferred request options, the payload is compressed before being signed,
+// and the upload progress is tracked on the final payload.
+const (
+	stageCompress = iota
+	stageSign
+	stageUploadProgress
+)
+
+// settingsOf returns the settings of the HTTP request, it's never nil.
+func settingsOf(hr *http.Request) *requestSettings {
+	if s, ok := hr.Context().Value(requestSettingsKey{}).(*requestSettings); ok && s != nil {
+		return s
+	}
+	return new(requestSettings)
+}
+
+// withSettings returns a shallow copy of hr with its settings updated by fn.
+func withSettings(hr *http.Request, fn func(s *requestSettings)) *http.Request {
+	s := new(requestSettings)
+	*s = *settingsOf(hr)
+	s.deferred = append([]deferredOption(nil), s.deferred...)
+	fn(s)
+	return hr.WithContext(context.WithValue(hr.Context(), requestSettingsKey{}, s))
+}
+
+// withoutSettings returns a copy of ctx which hides the settings of sreq, e.g. for a request
+// sent on behalf of another one. The other values of ctx are kept.
+func withoutSettings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestSettingsKey{}, (*requestSettings)(nil))
+}
+
+// deferOption schedules opt to be applied after all the other request options,
+// so that it sees the final HTTP request whatever the order of the options is.
+func deferOption(hr *http.Request, stage int, opt RequestOption) *http.Request {
+	return withSettings(hr, func(s *requestSettings) {
+		s.deferred = append(s.deferred, deferredOption{stage: stage, opt: opt})
+	})
+}
+
+// applyDeferred applies the deferred request options of hr by stage.
+func applyDeferred(hr *http.Request) (*http.Request, error) {
+	var deferred []deferredOption
+	hr = withSettings(hr, func(s *requestSettings) {
+		deferred, s.deferred = s.deferred, nil
+	})
+	sort.SliceStable(deferred, func(i, j int) bool {
+		return deferred[i].stage < deferred[j].stage
+	})
+
+	var err error
+	for _, d := range deferred {
+		hr, err = d.opt(hr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hr, nil
 }
 
 // WithHost specifies the host on which the URL is sought.
@@ -196,6 +297,19 @@ func WithHeaders(headers Headers) RequestOption {
 	}
 }
 
+// WithMultiHeaders sets headers of the HTTP request, every value of a key is sent in order.
+func WithMultiHeaders(headers MultiHeaders) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		for k, v := range headers {
+			hr.Header.Del(k)
+			for _, value := range v {
+				hr.Header.Add(k, value)
+			}
+		}
+		return hr, nil
+	}
+}
+
 // WithQuery sets query params of the HTTP request.
 func WithQuery(params Params) RequestOption {
 	return func(hr *http.Request) (*http.Request, error) {
@@ -208,6 +322,21 @@ func WithQuery(params Params) RequestOption {
 	}
 }
 
+// WithMultiQuery sets query params of the HTTP request, every value of a key is sent in order.
+func WithMultiQuery(params MultiParams) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		query := hr.URL.Query()
+		for k, v := range params {
+			query.Del(k)
+			for _, value := range v {
+				query.Add(k, value)
+			}
+		}
+		hr.URL.RawQuery = query.Encode()
+		return hr, nil
+	}
+}
+
 // WithRaw sets raw bytes payload of the HTTP request.
 func WithRaw(raw []byte, contentType string) RequestOption {
 	return func(hr *http.Request) (*http.Request, error) {
@@ -249,19 +378,29 @@ func WithForm(form Form) RequestOption {
 		for k, v := range form {
 			data.Set(k, v)
 		}
+		return withFormValues(hr, data)
+	}
+}
 
-		r := strings.NewReader(data.Encode())
-		hr.Body = ioutil.NopCloser(r)
-		hr.ContentLength = int64(r.Len())
-		snapshot := *r
-		hr.GetBody = func() (io.ReadCloser, error) {
-			r := snapshot
-			return ioutil.NopCloser(&r), nil
-		}
+// WithMultiForm sets form payload of the HTTP request, every value of a key is sent in order.
+func WithMultiForm(form MultiForm) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		return withFormValues(hr, stdurl.Values(form))
+	}
+}
 
-		hr.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		return hr, nil
+func withFormValues(hr *http.Request, data stdurl.Values) (*http.Request, error) {
+	r := strings.NewReader(data.Encode())
+	hr.Body = ioutil.NopCloser(r)
+	hr.ContentLength = int64(r.Len())
+	snapshot := *r
+	hr.GetBody = func() (io.ReadCloser, error) {
+		r := snapshot
+		return ioutil.NopCloser(&r), nil
 	}
+
+	hr.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return hr, nil
 }
 
 // WithJSON sets json payload of the HTTP request.
@@ -289,39 +428,17 @@ func WithJSON(data JSON, escapeHTML bool) RequestOption {
 // WithFiles sets files payload of the HTTP request.
 func WithFiles(files Files) RequestOption {
 	return func(hr *http.Request) (*http.Request, error) {
-		for fieldName, filePath := range files {
-			if _, err := ExistsFile(filePath); err != nil {
-				return nil, fmt.Errorf("sreq: file for %q not ready: %v", fieldName, err)
-			}
+		fieldNames := make([]string, 0, len(files))
+		for fieldName := range files {
+			fieldNames = append(fieldNames, fieldName)
 		}
+		sort.Strings(fieldNames)
 
-		r, w := io.Pipe()
-		mw := multipart.NewWriter(w)
-		go func() {
-			defer w.Close()
-			defer mw.Close()
-
-			for fieldName, filePath := range files {
-				fileName := filepath.Base(filePath)
-				part, err := mw.CreateFormFile(fieldName, fileName)
-				if err != nil {
-					return
-				}
-				file, err := os.Open(filePath)
-				if err != nil {
-					return
-				}
-
-				_, err = io.Copy(part, file)
-				if err != nil || file.Close() != nil {
-					return
-				}
-			}
-		}()
-
-		hr.Body = r
-		hr.Header.Set("Content-Type", mw.FormDataContentType())
-		return hr, nil
+		mp := NewMultipart()
+		for _, fieldName := range fieldNames {
+			mp.AddFile(FileFromPath(fieldName, files[fieldName]))
+		}
+		return WithMultipart(mp)(hr)
 	}
 }
 
@@ -362,6 +479,55 @@ func WithContext(ctx context.Context) RequestOption {
 		if ctx == nil {
 			return nil, errors.New("sreq: nil Context")
 		}
+		// The settings of sreq are carried over, e.g. the retry policy set by WithRetry.
+		if s := hr.Context().Value(requestSettingsKey{}); s != nil {
+			ctx = context.WithValue(ctx, requestSettingsKey{}, s)
+		}
 		return hr.WithContext(ctx), nil
 	}
 }
+
+// bodyBytes reads the whole payload of the HTTP request,
+// the caller should reset the body with setBody afterwards.
+func bodyBytes(hr *http.Request) ([]byte, error) {
+	if hr.Body == nil || hr.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body := hr.Body
+	if hr.GetBody != nil {
+		var err error
+		body, err = hr.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+// setBody sets a replayable payload of the HTTP request.
+func setBody(hr *http.Request, b []byte) {
+	if hr.Body != nil {
+		hr.Body.Close()
+	}
+
+	hr.Body = ioutil.NopCloser(bytes.NewReader(b))
+	hr.ContentLength = int64(len(b))
+	hr.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+}
+
+// payload returns the whole payload of the HTTP request, the body is restored if it's consumed.
+func payload(hr *http.Request) ([]byte, error) {
+	b, err := bodyBytes(hr)
+	if err != nil {
+		return nil, err
+	}
+	if hr.GetBody == nil && hr.Body != nil && hr.Body != http.NoBody {
+		setBody(hr, b)
+	}
+	return b, nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 	"time"
 
@@ -155,6 +156,57 @@ func TestRequest(t *testing.T) {
 	}
 }
 
+func TestNewRequest(t *testing.T) {
+	_, err := sreq.NewRequest("@", "http://httpbin.org/get")
+	if err == nil {
+		t.Error("NewRequest method unchecked")
+	}
+
+	req := sreq.New(nil)
+	req.SetDefaultRequestOpts(
+		sreq.WithHeaders(sreq.Headers{
+			"Origin": "http://httpbin.org",
+		}),
+	)
+	httpReq, err := req.NewRequest(sreq.MethodPost, "http://httpbin.org/post",
+		sreq.WithQuery(sreq.Params{
+			"key1": "value1",
+		}),
+		sreq.WithText("hello world"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if httpReq.Method != sreq.MethodPost || httpReq.URL.String() != "http://httpbin.org/post?key1=value1" {
+		t.Errorf("NewRequest got: %s %s", httpReq.Method, httpReq.URL)
+	}
+	if httpReq.Header.Get("Origin") != "http://httpbin.org" || httpReq.Header.Get("User-Agent") != "sreq "+sreq.Version {
+		t.Errorf("NewRequest headers got: %v", httpReq.Header)
+	}
+	if httpReq.GetBody == nil || httpReq.ContentLength != int64(len("hello world")) {
+		t.Error("NewRequest body not prepared")
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Signature")))
+	}))
+	defer ts.Close()
+
+	httpReq, err = req.NewRequest(sreq.MethodGet, ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Set("X-Signature", "signed")
+	data, err := req.Send(httpReq).EnsureStatusOk().Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "signed" {
+		t.Errorf("Send prepared request got: %s, want: signed", data)
+	}
+}
+
 func TestWithQuery(t *testing.T) {
 	type response struct {
 		Args map[string]string `json:"args"`
@@ -178,6 +230,68 @@ func TestWithQuery(t *testing.T) {
 	}
 }
 
+func TestWithMultiQuery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"args":   r.URL.Query(),
+			"accept": r.Header["Accept"],
+		})
+	}))
+	defer ts.Close()
+
+	type response struct {
+		Args   map[string][]string `json:"args"`
+		Accept []string            `json:"accept"`
+	}
+
+	resp := new(response)
+	err := sreq.
+		Get(ts.URL+"?id=0&key=value",
+			sreq.WithMultiQuery(sreq.MultiParams{
+				"id": {"1", "2"},
+			}),
+			sreq.WithMultiHeaders(sreq.MultiHeaders{
+				"Accept": {"text/html", "application/json"},
+			}),
+		).
+		EnsureStatusOk().
+		JSON(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Args["id"], []string{"1", "2"}) || resp.Args["key"][0] != "value" {
+		t.Errorf("Set multi-value params got: %v", resp.Args)
+	}
+	if !reflect.DeepEqual(resp.Accept, []string{"text/html", "application/json"}) {
+		t.Errorf("Set multi-value headers got: %v", resp.Accept)
+	}
+}
+
+func TestWithMultiForm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		json.NewEncoder(w).Encode(r.PostForm)
+	}))
+	defer ts.Close()
+
+	form := make(map[string][]string)
+	err := sreq.
+		Post(ts.URL,
+			sreq.WithMultiForm(sreq.MultiForm{
+				"id":  {"3", "1", "2"},
+				"key": {"value"},
+			}),
+		).
+		EnsureStatusOk().
+		JSON(&form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(form["id"], []string{"3", "1", "2"}) || form["key"][0] != "value" {
+		t.Errorf("Send multi-value form got: %v", form)
+	}
+}
+
 func TestWithHost(t *testing.T) {
 	type response struct {
 		Host string `json:"host"`
@@ -1,36 +1,108 @@
 package sreq
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	stdurl "net/url"
 	"os"
 )
 
+// MaxErrorBodySize specifies the max bytes of the HTTP response body that HTTPError holds.
+const MaxErrorBodySize = 4096
+
 type (
 	// Response wraps the original HTTP response and the potential error.
 	Response struct {
 		R   *http.Response
 		Err error
+
+		body     []byte
+		bodyErr  error
+		buffered bool
+	}
+
+	// HTTPError is the error that EnsureStatus, EnsureStatus2xx and EnsureStatusOk return
+	// if the HTTP response's status code is unexpected.
+	HTTPError struct {
+		// StatusCode specifies the status code of the HTTP response.
+		StatusCode int
+
+		// Method specifies the method of the HTTP request.
+		Method string
+
+		// URL specifies the URL of the HTTP request, with the password redacted.
+		URL string
+
+		// Header specifies the headers of the HTTP response.
+		Header http.Header
+
+		// Body holds the leading MaxErrorBodySize bytes of the HTTP response body at most.
+		Body []byte
 	}
 )
 
+// Error implements error interface.
+func (e *HTTPError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("sreq: bad status: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("sreq: bad status: %d, %s %s", e.StatusCode, e.Method, e.URL)
+}
+
+// WithMaxBodySize limits the size of the HTTP response body that Raw, Text and JSON
+// read into memory, an error would be returned if the body exceeds it.
+func WithMaxBodySize(n int64) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		return withSettings(hr, func(s *requestSettings) {
+			s.maxBodySize = n
+		}), nil
+	}
+}
+
 // Resolve resolves r and returns its original HTTP response.
 func (r *Response) Resolve() (*http.Response, error) {
 	return r.R, r.Err
 }
 
 // Raw decodes the HTTP response body of r and returns its raw data.
+// The body is read only once and cached, so Raw, Text, JSON and Save can be called repeatedly.
 func (r *Response) Raw() ([]byte, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
+	if !r.buffered {
+		r.buffer()
+	}
+
+	return r.body, r.bodyErr
+}
+
+// buffer reads the HTTP response body of r into memory, and replaces the original body
+// with the cached data so that it's still readable after resolving r.
+func (r *Response) buffer() {
 	defer r.R.Body.Close()
 
-	return ioutil.ReadAll(r.R.Body)
+	var maxBodySize int64
+	if r.R.Request != nil {
+		maxBodySize = settingsOf(r.R.Request).maxBodySize
+	}
+
+	var body io.Reader = r.R.Body
+	if maxBodySize > 0 {
+		body = io.LimitReader(body, maxBodySize+1)
+	}
+	r.body, r.bodyErr = ioutil.ReadAll(body)
+	if r.bodyErr == nil && maxBodySize > 0 && int64(len(r.body)) > maxBodySize {
+		r.body, r.bodyErr = nil, fmt.Errorf("sreq: response body exceeds %d bytes", maxBodySize)
+	}
+
+	r.buffered = true
+	r.R.Body = ioutil.NopCloser(bytes.NewReader(r.body))
 }
 
 // Text decodes the HTTP response body of r and returns the text representation of its raw data.
@@ -41,12 +113,12 @@ func (r *Response) Text() (string, error) {
 
 // JSON decodes the HTTP response body of r and unmarshals its JSON-encoded data into v.
 func (r *Response) JSON(v interface{}) error {
-	if r.Err != nil {
-		return r.Err
+	b, err := r.Raw()
+	if err != nil {
+		return err
 	}
-	defer r.R.Body.Close()
 
-	return json.NewDecoder(r.R.Body).Decode(v)
+	return json.NewDecoder(bytes.NewReader(b)).Decode(v)
 }
 
 // Cookie returns the HTTP response cookie by name.
@@ -75,7 +147,7 @@ func (r *Response) EnsureStatus2xx() *Response {
 		return r
 	}
 	if r.R.StatusCode/100 != 2 {
-		r.Err = fmt.Errorf("sreq: bad status: %d", r.R.StatusCode)
+		r.Err = r.httpError()
 	}
 	return r
 }
@@ -86,24 +158,83 @@ func (r *Response) EnsureStatus(code int) *Response {
 		return r
 	}
 	if r.R.StatusCode != code {
-		r.Err = fmt.Errorf("sreq: bad status: %d", r.R.StatusCode)
+		r.Err = r.httpError()
 	}
 	return r
 }
 
+func (r *Response) httpError() *HTTPError {
+	e := &HTTPError{
+		StatusCode: r.R.StatusCode,
+		Header:     r.R.Header,
+	}
+	if r.R.Request != nil {
+		e.Method = r.R.Request.Method
+		e.URL = redactURL(r.R.Request.URL)
+	}
+
+	if r.buffered {
+		e.Body = r.body
+		if len(e.Body) > MaxErrorBodySize {
+			e.Body = e.Body[:MaxErrorBodySize]
+		}
+	} else {
+		e.Body, r.R.Body = peekBody(r.R.Body, MaxErrorBodySize)
+	}
+	return e
+}
+
+// peekBody reads at most n bytes from body, and returns them with
+// a body that replays them before the rest.
+// redactURL returns the string form of u with the password replaced, like net/http does in its errors.
+func redactURL(u *stdurl.URL) string {
+	if u == nil {
+		return ""
+	}
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+	redacted := *u
+	redacted.User = stdurl.UserPassword(u.User.Username(), "xxxxx")
+	return redacted.String()
+}
+
+func peekBody(body io.ReadCloser, n int64) ([]byte, io.ReadCloser) {
+	if body == nil || body == http.NoBody {
+		return nil, body
+	}
+
+	b, _ := ioutil.ReadAll(io.LimitReader(body, n))
+	return b, &struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(b), body),
+		Closer: body,
+	}
+}
+
 // Save saves the HTTP response into a file.
 func (r *Response) Save(filename string) error {
 	if r.Err != nil {
 		return r.Err
 	}
 
-	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0664)
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	defer r.R.Body.Close()
 
+	if r.buffered {
+		if r.bodyErr != nil {
+			return r.bodyErr
+		}
+		_, err = file.Write(r.body)
+		return err
+	}
+
+	defer r.R.Body.Close()
 	_, err = io.Copy(file, r.R.Body)
 	return err
 }
@@ -1,9 +1,14 @@
 package sreq_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/winterssy/sreq"
@@ -56,6 +61,63 @@ func TestResponse_Text(t *testing.T) {
 	}
 }
 
+func TestResponse_Raw(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"msg":"hello world"}`)
+	}))
+	defer ts.Close()
+
+	resp := sreq.Get(ts.URL).EnsureStatusOk()
+	data, err := resp.Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != `{"msg":"hello world"}` {
+		t.Errorf("Response_Text got: %s", data)
+	}
+
+	v := make(map[string]string)
+	if err = resp.JSON(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v["msg"] != "hello world" {
+		t.Error("Response_JSON after Response_Text test failed")
+	}
+
+	b, err := resp.Raw()
+	if err != nil || string(b) != data {
+		t.Error("Response_Raw after Response_JSON test failed")
+	}
+
+	_, err = sreq.
+		Get(ts.URL,
+			sreq.WithMaxBodySize(8),
+		).
+		Raw()
+	if err == nil {
+		t.Error("Max body size unchecked")
+	}
+
+	_, err = sreq.
+		Get(ts.URL,
+			sreq.WithMaxBodySize(8),
+			sreq.WithContext(context.Background()),
+		).
+		Raw()
+	if err == nil {
+		t.Error("Max body size with WithContext unchecked")
+	}
+
+	data, err = sreq.
+		Get(ts.URL,
+			sreq.WithMaxBodySize(1024),
+		).
+		Text()
+	if err != nil || data != `{"msg":"hello world"}` {
+		t.Errorf("Response_Text with max body size got: %s, %v", data, err)
+	}
+}
+
 func TestResponse_Cookie(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.SetCookie(w, &http.Cookie{
@@ -131,6 +193,58 @@ func TestResponse_EnsureStatus(t *testing.T) {
 	}
 }
 
+func TestHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "10086")
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, strings.Repeat("x", sreq.MaxErrorBodySize+1))
+	}))
+	defer ts.Close()
+
+	resp := sreq.New(nil).
+		Put(ts.URL).
+		EnsureStatus2xx()
+	var httpErr *sreq.HTTPError
+	if !errors.As(resp.Err, &httpErr) {
+		t.Fatalf("EnsureStatus2xx got error: %v, want: *sreq.HTTPError", resp.Err)
+	}
+	if httpErr.StatusCode != http.StatusConflict || httpErr.Method != sreq.MethodPut ||
+		httpErr.URL != ts.URL || httpErr.Header.Get("X-Request-Id") != "10086" {
+		t.Errorf("HTTPError got: %+v", httpErr)
+	}
+	if len(httpErr.Body) != sreq.MaxErrorBodySize {
+		t.Errorf("HTTPError body got length: %d, want: %d", len(httpErr.Body), sreq.MaxErrorBodySize)
+	}
+
+	// The body is still readable after the status check.
+	b, err := ioutil.ReadAll(resp.R.Body)
+	resp.R.Body.Close()
+	if err != nil || len(b) != sreq.MaxErrorBodySize+1 {
+		t.Errorf("HTTP response body got length: %d, want: %d", len(b), sreq.MaxErrorBodySize+1)
+	}
+}
+
+func TestHTTPError_RedactPassword(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	url := strings.Replace(ts.URL, "http://", "http://admin:secret@", 1)
+	err := sreq.New(nil).
+		Get(url).
+		EnsureStatusOk().
+		Err
+	var httpErr *sreq.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("EnsureStatusOk got error: %v, want: *sreq.HTTPError", err)
+	}
+	want := strings.Replace(ts.URL, "http://", "http://admin:xxxxx@", 1)
+	if httpErr.URL != want || strings.Contains(err.Error(), "secret") {
+		t.Errorf("HTTPError URL got: %s, want: %s", httpErr.URL, want)
+	}
+}
+
 func TestResponse_Save(t *testing.T) {
 	const testFileName = "testdata.json"
 	err := sreq.Get("http://httpbin.org/get").
@@ -140,3 +254,26 @@ func TestResponse_Save(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestResponse_Save_Truncate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "short")
+	}))
+	defer ts.Close()
+
+	file, err := ioutil.TempFile("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("a much longer content")
+	file.Close()
+
+	if err = sreq.Get(ts.URL).EnsureStatusOk().Save(file.Name()); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadFile(file.Name())
+	if string(b) != "short" {
+		t.Errorf("Response_Save got: %q, want: %q", b, "short")
+	}
+}
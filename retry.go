@@ -0,0 +1,232 @@
+package sreq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type (
+	// RetryPolicy specifies how sreq retries an HTTP request.
+	RetryPolicy struct {
+		// MaxAttempts specifies the max attempts of per HTTP request, including the first one.
+		// Values less than 2 disable retrying.
+		MaxAttempts int
+
+		// MinBackoff specifies the backoff before the first retry, it doubles for each subsequent retry.
+		MinBackoff time.Duration
+
+		// MaxBackoff specifies the upper bound of the backoff, no limit if it's not positive.
+		// It also bounds the Retry-After header, retrying stops if the server asks to wait longer,
+		// which is 1 minute if MaxBackoff is not positive.
+		MaxBackoff time.Duration
+
+		// RetryNonIdempotent allows retrying the requests with non-idempotent methods, like POST and PATCH,
+		// which may cause duplicate side effects. The requests with an Idempotency-Key or X-Idempotency-Key
+		// header are considered idempotent.
+		RetryNonIdempotent bool
+
+		// Condition reports whether an HTTP request should be retried according to its response.
+		// If nil, DefaultRetryCondition is used. It's not called for non-idempotent requests
+		// unless RetryNonIdempotent is set.
+		Condition func(resp *Response) bool
+	}
+)
+
+// maxRetryAfter bounds the Retry-After header if RetryPolicy.MaxBackoff is not positive.
+const maxRetryAfter = time.Minute
+
+// DefaultRetryCondition reports whether an HTTP request should be retried according to its response.
+// It retries on network errors except cancellation and open circuits, 429 and 5xx status codes except 501.
+func DefaultRetryCondition(resp *Response) bool {
+	if resp.Err != nil {
+		var circuitErr *CircuitOpenError
+		return !errors.Is(resp.Err, context.Canceled) && !errors.Is(resp.Err, context.DeadlineExceeded) &&
+			!errors.As(resp.Err, &circuitErr)
+	}
+
+	code := resp.R.StatusCode
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented)
+}
+
+// SetRetryPolicy sets the default retry policy for per HTTP request.
+func SetRetryPolicy(policy *RetryPolicy) {
+	std.SetRetryPolicy(policy)
+}
+
+// SetRetryPolicy sets the default retry policy for per HTTP request.
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	c.mux.Lock()
+	c.RetryPolicy = policy
+	c.mux.Unlock()
+}
+
+// WithRetry sets the retry policy of the HTTP request, it overrides the client's one.
+// A nil policy disables retrying.
+func WithRetry(policy *RetryPolicy) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		return withSettings(hr, func(s *requestSettings) {
+			s.retryPolicy, s.hasRetryPolicy = policy, true
+		}), nil
+	}
+}
+
+func (c *Client) retryPolicy(httpReq *http.Request) *RetryPolicy {
+	if s := settingsOf(httpReq); s.hasRetryPolicy {
+		return s.retryPolicy
+	}
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.RetryPolicy
+}
+
+func (p *RetryPolicy) shouldRetry(httpReq *http.Request, resp *Response) bool {
+	if !p.RetryNonIdempotent && !idempotent(httpReq) {
+		return false
+	}
+	if p.Condition != nil {
+		return p.Condition(resp)
+	}
+	return DefaultRetryCondition(resp)
+}
+
+// backoff returns how long to wait before the given retry attempt, which starts from 1.
+// It reports false if the server asks to wait longer than the policy allows.
+func (p *RetryPolicy) backoff(attempt int, resp *Response) (time.Duration, bool) {
+	if d, ok := retryAfter(resp); ok {
+		limit := p.MaxBackoff
+		if limit <= 0 {
+			limit = maxRetryAfter
+		}
+		return d, d <= limit
+	}
+
+	d := p.MinBackoff
+	for i := 1; i < attempt && d > 0; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0, true
+	}
+
+	// Equal jitter, keeps at least half of the backoff.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1)), true
+}
+
+// idempotent reports whether the HTTP request can be sent multiple times without side effects.
+func idempotent(httpReq *http.Request) bool {
+	switch httpReq.Method {
+	case "", MethodGet, MethodHead, MethodOptions, MethodTrace, MethodPut, MethodDelete:
+		return true
+	}
+	_, ok := httpReq.Header["Idempotency-Key"]
+	if !ok {
+		_, ok = httpReq.Header["X-Idempotency-Key"]
+	}
+	return ok
+}
+
+// retryAfter parses the Retry-After header of 429 and 503 responses.
+func retryAfter(resp *Response) (time.Duration, bool) {
+	if resp.Err != nil {
+		return 0, false
+	}
+	if code := resp.R.StatusCode; code != http.StatusTooManyRequests && code != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	v := resp.R.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// rewindBody resets the body of httpReq so that it can be sent again.
+func rewindBody(httpReq *http.Request) error {
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return nil
+	}
+	if httpReq.GetBody == nil {
+		return errors.New("sreq: request body can not be replayed")
+	}
+
+	body, err := httpReq.GetBody()
+	if err != nil {
+		return err
+	}
+	if fn := settingsOf(httpReq).uploadProgress; fn != nil {
+		body = newProgressReader(body, httpReq.ContentLength, fn)
+	}
+	httpReq.Body = body
+	return nil
+}
+
+// drainBody discards the rest of the response body so that the connection can be reused.
+func drainBody(resp *Response) {
+	if resp.Err != nil || resp.R == nil || resp.R.Body == nil {
+		return
+	}
+
+	io.CopyN(ioutil.Discard, resp.R.Body, 4096)
+	resp.R.Body.Close()
+}
+
+func (c *Client) sendWithRetry(httpReq *http.Request, policy *RetryPolicy) *Response {
+	resp := c.do(httpReq)
+	for attempt := 1; attempt < policy.MaxAttempts && policy.shouldRetry(httpReq, resp); attempt++ {
+		wait, ok := policy.backoff(attempt, resp)
+		if !ok {
+			return resp
+		}
+		if deadline, ok := httpReq.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			// The retry would not be sent before the deadline.
+			return resp
+		}
+		if rewindBody(httpReq) != nil {
+			return resp
+		}
+
+		drainBody(resp)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-httpReq.Context().Done():
+			timer.Stop()
+			return &Response{
+				Err: httpReq.Context().Err(),
+			}
+		case <-timer.C:
+		}
+
+		resp = c.do(httpReq)
+	}
+
+	return resp
+}
@@ -0,0 +1,246 @@
+package sreq_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) != "hello world" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	req.SetRetryPolicy(&sreq.RetryPolicy{
+		MaxAttempts:        3,
+		MinBackoff:         time.Millisecond,
+		MaxBackoff:         10 * time.Millisecond,
+		RetryNonIdempotent: true,
+	})
+
+	_, err := req.
+		Post(ts.URL,
+			sreq.WithText("hello world"),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("RetryPolicy got attempts: %d, want: %d", attempts, 3)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	_, err = req.
+		Post(ts.URL,
+			sreq.WithText("hello world"),
+			sreq.WithRetry(nil),
+		).
+		EnsureStatus(http.StatusServiceUnavailable).
+		Resolve()
+	if err != nil {
+		t.Error(err)
+	}
+	if attempts != 1 {
+		t.Errorf("WithRetry got attempts: %d, want: %d", attempts, 1)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	_, err := sreq.
+		Get(ts.URL,
+			sreq.WithRetry(&sreq.RetryPolicy{
+				MaxAttempts: 4,
+				MinBackoff:  time.Millisecond,
+			}),
+		).
+		EnsureStatus(http.StatusInternalServerError).
+		Resolve()
+	if err != nil {
+		t.Error(err)
+	}
+	if attempts != 4 {
+		t.Errorf("WithRetry got attempts: %d, want: %d", attempts, 4)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	_, err = sreq.
+		Get(ts.URL,
+			sreq.WithRetry(&sreq.RetryPolicy{
+				MaxAttempts: 4,
+				Condition: func(resp *sreq.Response) bool {
+					return resp.Err != nil
+				},
+			}),
+		).
+		Resolve()
+	if err != nil {
+		t.Error(err)
+	}
+	if attempts != 1 {
+		t.Errorf("RetryPolicy_Condition got attempts: %d, want: %d", attempts, 1)
+	}
+}
+
+func TestWithRetry_WithContext(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	policy := &sreq.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+	}
+	withRetry, withContext := sreq.WithRetry(policy), sreq.WithContext(context.Background())
+
+	req := sreq.New(nil)
+	for _, opts := range [][]sreq.RequestOption{
+		{withRetry, withContext},
+		{withContext, withRetry},
+	} {
+		atomic.StoreInt32(&attempts, 0)
+		req.Get(ts.URL, opts...).Raw()
+		if attempts != 3 {
+			t.Errorf("WithRetry with WithContext got attempts: %d, want: %d", attempts, 3)
+		}
+	}
+
+	// The default retry policy set by WithRetry is kept as well.
+	req.SetDefaultRequestOpts(withRetry)
+	atomic.StoreInt32(&attempts, 0)
+	req.Get(ts.URL, withContext).Raw()
+	if attempts != 3 {
+		t.Errorf("Default WithRetry with WithContext got attempts: %d, want: %d", attempts, 3)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	start := time.Now()
+	_, err := sreq.
+		Get(ts.URL,
+			sreq.WithRetry(&sreq.RetryPolicy{
+				MaxAttempts: 2,
+				MinBackoff:  time.Millisecond,
+			}),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Retry-After not honored, elapsed: %s", elapsed)
+	}
+}
+
+func TestRetryPolicy_Idempotent(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	req.SetRetryPolicy(&sreq.RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+	})
+
+	tests := []struct {
+		method   string
+		opts     []sreq.RequestOption
+		attempts int32
+	}{
+		{sreq.MethodPost, nil, 1},
+		{sreq.MethodPatch, nil, 1},
+		{sreq.MethodPut, nil, 2},
+		{sreq.MethodDelete, nil, 2},
+		{
+			sreq.MethodPost,
+			[]sreq.RequestOption{
+				sreq.WithHeaders(sreq.Headers{
+					"Idempotency-Key": "10086",
+				}),
+			},
+			2,
+		},
+	}
+	for _, test := range tests {
+		atomic.StoreInt32(&attempts, 0)
+		req.Request(test.method, ts.URL, test.opts...).Raw()
+		if attempts != test.attempts {
+			t.Errorf("RetryPolicy %s got attempts: %d, want: %d", test.method, attempts, test.attempts)
+		}
+	}
+}
+
+func TestRetryAfter_Limit(t *testing.T) {
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	for _, maxBackoff := range []time.Duration{0, time.Second} {
+		atomic.StoreInt32(&attempts, 0)
+		start := time.Now()
+		_, err := sreq.New(nil).
+			Get(ts.URL,
+				sreq.WithRetry(&sreq.RetryPolicy{
+					MaxAttempts: 2,
+					MaxBackoff:  maxBackoff,
+				}),
+			).
+			EnsureStatus(http.StatusServiceUnavailable).
+			Resolve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempts != 1 || time.Since(start) > time.Second {
+			t.Errorf("Retry-After beyond the limit got attempts: %d, elapsed: %s", attempts, time.Since(start))
+		}
+	}
+}
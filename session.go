@@ -0,0 +1,119 @@
+package sreq
+
+import (
+	"errors"
+	stdurl "net/url"
+	"strings"
+)
+
+// SetBaseURL sets the URL that relative request URLs resolve against.
+func SetBaseURL(url string) error {
+	return std.SetBaseURL(url)
+}
+
+// SetBaseURL sets the URL that relative request URLs resolve against.
+// The path of the base URL is treated as a directory, e.g. "users" resolves against
+// "http://example.com/api/v1" to "http://example.com/api/v1/users", whereas "/users" resolves
+// to "http://example.com/users" as RFC 3986 specifies. An empty url disables the resolution.
+func (c *Client) SetBaseURL(url string) error {
+	if url != "" {
+		u, err := stdurl.Parse(url)
+		if err != nil {
+			return err
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return errors.New("sreq: base URL must be absolute")
+		}
+	}
+
+	c.mux.Lock()
+	c.BaseURL = url
+	c.mux.Unlock()
+	return nil
+}
+
+// NewSession returns a client whose base URL is baseURL, which is resolved against
+// the base URL of std if it's relative.
+func NewSession(baseURL string, opts ...RequestOption) (*Client, error) {
+	return std.NewSession(baseURL, opts...)
+}
+
+// NewSession returns a child client of c for a group of related requests, like the calls to a service.
+// It shares the transport of c and inherits the default request options, the retry policy and
+// the middlewares of c, with opts appended to the default request options. It has its own cookie jar,
+// and its base URL is baseURL, which is resolved against the base URL of c if it's relative.
+// Later changes to c are not propagated to the child.
+func (c *Client) NewSession(baseURL string, opts ...RequestOption) (*Client, error) {
+	baseURL, err := c.resolveURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	child := c.Derive(opts...)
+	child.C.Jar = NewCookieJar()
+	if err = child.SetBaseURL(baseURL); err != nil {
+		return nil, err
+	}
+	return child, nil
+}
+
+// Derive returns a child client of std with extra default request options.
+func Derive(opts ...RequestOption) *Client {
+	return std.Derive(opts...)
+}
+
+// Derive returns a child client of c with opts appended to the default request options, it's cheap
+// since the transport, i.e. the connection pool, and the cookie jar are shared with c.
+// The child has a copy of the HTTP client of c, so setting its timeouts, TLS or debug mode
+// replaces its own transport and leaves c untouched.
+// The base URL, the retry policy and the middlewares are inherited, later changes to c are not
+// propagated to the child and vice versa.
+func (c *Client) Derive(opts ...RequestOption) *Client {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	hc := *c.C
+	requestOpts := make([]RequestOption, 0, len(c.RequestOptions)+len(opts))
+	requestOpts = append(requestOpts, c.RequestOptions...)
+	return &Client{
+		C:              &hc,
+		RequestOptions: append(requestOpts, opts...),
+		RetryPolicy:    c.RetryPolicy,
+		Middlewares:    append([]Middleware(nil), c.Middlewares...),
+		BaseURL:        c.BaseURL,
+	}
+}
+
+// resolveURL resolves url against the base URL of c if it's relative.
+func (c *Client) resolveURL(url string) (string, error) {
+	c.mux.RLock()
+	baseURL := c.BaseURL
+	c.mux.RUnlock()
+	if baseURL == "" {
+		return url, nil
+	}
+	if url == "" {
+		return baseURL, nil
+	}
+
+	u, err := stdurl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+	if u.IsAbs() {
+		return url, nil
+	}
+
+	base, err := stdurl.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		// Treat the last segment of the base path as a directory.
+		base.Path += "/"
+		if base.RawPath != "" {
+			base.RawPath += "/"
+		}
+	}
+	return base.ResolveReference(u).String(), nil
+}
@@ -0,0 +1,161 @@
+package sreq_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+func TestSetBaseURL(t *testing.T) {
+	req := sreq.New(nil)
+	if err := req.SetBaseURL("/api"); err == nil {
+		t.Error("SetBaseURL relative URL unchecked")
+	}
+	if err := req.SetBaseURL("http://example.com/api/v1?key=value"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"", "http://example.com/api/v1?key=value"},
+		{"users", "http://example.com/api/v1/users"},
+		{"users/1?fields=name", "http://example.com/api/v1/users/1?fields=name"},
+		{"../v2/users", "http://example.com/api/v2/users"},
+		{"/users", "http://example.com/users"},
+		{"https://httpbin.org/get", "https://httpbin.org/get"},
+	}
+	for _, test := range tests {
+		httpReq, err := req.NewRequest(sreq.MethodGet, test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := httpReq.URL.String(); got != test.want {
+			t.Errorf("Resolve %q got: %s, want: %s", test.url, got, test.want)
+		}
+	}
+}
+
+func TestNewSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/login":
+			http.SetCookie(w, &http.Cookie{Name: "token", Value: "sreq", Path: "/"})
+		default:
+			w.Header().Set("X-Path", r.URL.Path)
+			w.Header().Set("X-Service", r.Header.Get("X-Service"))
+			w.Header().Set("X-Trace", r.Header.Get("X-Trace"))
+			if c, err := r.Cookie("token"); err == nil {
+				w.Header().Set("X-Token", c.Value)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	parent := sreq.New(nil)
+	parent.SetDefaultRequestOpts(
+		sreq.WithHeaders(sreq.Headers{
+			"X-Trace": "1",
+		}),
+	)
+	if err := parent.SetBaseURL(ts.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := parent.NewSession("users",
+		sreq.WithHeaders(sreq.Headers{
+			"X-Service": "users",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders, err := parent.NewSession("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = users.Post("login").EnsureStatusOk().Resolve(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := users.Get("profile").EnsureStatusOk().Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-Path") != "/users/profile" || resp.Header.Get("X-Service") != "users" ||
+		resp.Header.Get("X-Trace") != "1" || resp.Header.Get("X-Token") != "sreq" {
+		t.Errorf("Session response headers got: %v", resp.Header)
+	}
+
+	// Sessions have their own cookie jars.
+	resp, err = orders.Get("1").EnsureStatusOk().Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-Path") != "/orders/1" || resp.Header.Get("X-Service") != "" ||
+		resp.Header.Get("X-Token") != "" {
+		t.Errorf("Session response headers got: %v", resp.Header)
+	}
+
+	if _, err = parent.NewSession("http://%41"); err == nil {
+		t.Error("NewSession base URL unchecked")
+	}
+}
+
+func TestDerive(t *testing.T) {
+	parent := sreq.New(nil)
+	parent.SetDefaultRequestOpts(
+		sreq.WithHeaders(sreq.Headers{
+			"X-Trace": "1",
+		}),
+	)
+
+	child := parent.Derive(
+		sreq.WithHeaders(sreq.Headers{
+			"X-Child": "1",
+		}),
+	)
+	parent.AddDefaultRequestOpts(
+		sreq.WithHeaders(sreq.Headers{
+			"X-Parent": "1",
+		}),
+	)
+	child.AddDefaultRequestOpts(
+		sreq.WithHeaders(sreq.Headers{
+			"X-Child2": "1",
+		}),
+	)
+
+	if child.C.Transport != parent.C.Transport || child.C.Jar != parent.C.Jar {
+		t.Error("Derive does not share the transport and the cookie jar")
+	}
+	transport := parent.C.Transport
+	if err := child.SetInsecureSkipVerify(true); err != nil {
+		t.Fatal(err)
+	}
+	if parent.C.Transport != transport || child.C.Transport == transport {
+		t.Error("Derive child transport changes propagated to the parent")
+	}
+
+	httpReq, err := child.NewRequest(sreq.MethodGet, "http://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := httpReq.Header
+	if h.Get("X-Trace") != "1" || h.Get("X-Child") != "1" || h.Get("X-Child2") != "1" || h.Get("X-Parent") != "" {
+		t.Errorf("Derive child headers got: %v", h)
+	}
+
+	httpReq, err = parent.NewRequest(sreq.MethodGet, "http://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = httpReq.Header
+	if h.Get("X-Trace") != "1" || h.Get("X-Parent") != "1" || h.Get("X-Child") != "" || h.Get("X-Child2") != "" {
+		t.Errorf("Derive parent headers got: %v", h)
+	}
+}
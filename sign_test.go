@@ -0,0 +1,232 @@
+package sreq_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/winterssy/sreq"
+)
+
+var awsTestCredentials = &sreq.AWSCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestWithAWSSigV4(t *testing.T) {
+	// Cases from the AWS Signature Version 4 test suite.
+	tests := []struct {
+		method    string
+		url       string
+		signature string
+	}{
+		{
+			method:    sreq.MethodGet,
+			url:       "http://example.amazonaws.com/",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			method:    sreq.MethodPost,
+			url:       "http://example.amazonaws.com/",
+			signature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			method:    sreq.MethodGet,
+			url:       "http://example.amazonaws.com/?Param2=value2&Param1=value1",
+			signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+
+	req := sreq.New(nil)
+	for _, test := range tests {
+		// The request is signed after all the options are applied.
+		httpReq, err := req.NewRequest(test.method, test.url,
+			sreq.WithAWSSigV4(awsTestCredentials, "us-east-1", "service"),
+			sreq.WithHeaders(sreq.Headers{
+				"X-Amz-Date": "20150830T123600Z",
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=" + test.signature
+		if got := httpReq.Header.Get("Authorization"); got != want {
+			t.Errorf("WithAWSSigV4 %s %s got: %s, want: %s", test.method, test.url, got, want)
+		}
+	}
+}
+
+func TestWithAWSSigV4_S3(t *testing.T) {
+	type result struct {
+		Authorization string
+		ContentSha256 string
+		SecurityToken string
+		Body          string
+	}
+	ch := make(chan result, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		ch <- result{
+			Authorization: r.Header.Get("Authorization"),
+			ContentSha256: r.Header.Get("X-Amz-Content-Sha256"),
+			SecurityToken: r.Header.Get("X-Amz-Security-Token"),
+			Body:          string(b),
+		}
+	}))
+	defer ts.Close()
+
+	cred := &sreq.AWSCredentials{
+		AccessKeyID:     awsTestCredentials.AccessKeyID,
+		SecretAccessKey: awsTestCredentials.SecretAccessKey,
+		SessionToken:    "token",
+	}
+	_, err := sreq.New(nil).
+		Put(ts.URL+"/bucket/hello world.txt",
+			sreq.WithText("hello world"),
+			sreq.WithAWSSigV4(cred, "us-east-1", "s3"),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-ch
+	sum := sha256.Sum256([]byte("hello world"))
+	if got.ContentSha256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("WithAWSSigV4 X-Amz-Content-Sha256 got: %s", got.ContentSha256)
+	}
+	if got.SecurityToken != "token" || got.Body != "hello world" {
+		t.Errorf("WithAWSSigV4 request got: %+v", got)
+	}
+	if !strings.Contains(got.Authorization, "/s3/aws4_request, SignedHeaders=content-type;host;"+
+		"x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=") {
+		t.Errorf("WithAWSSigV4 Authorization got: %s", got.Authorization)
+	}
+
+	_, err = sreq.WithAWSSigV4(nil, "us-east-1", "s3")(httptest.NewRequest(sreq.MethodGet, ts.URL, nil))
+	if err == nil {
+		t.Error("WithAWSSigV4 credentials unchecked")
+	}
+}
+
+func TestWithHMAC(t *testing.T) {
+	key := []byte("secret")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(b)
+		stringToSign := strings.Join([]string{
+			r.Method,
+			r.URL.EscapedPath(),
+			r.URL.Query().Encode(),
+			"host:" + r.Host,
+			"x-timestamp:" + r.Header.Get("X-Timestamp"),
+			hex.EncodeToString(sum[:]),
+		}, "\n")
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(stringToSign))
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	_, err := sreq.New(nil).
+		Post(ts.URL+"/api",
+			sreq.WithQuery(sreq.Params{
+				"b": "2",
+				"a": "1",
+			}),
+			sreq.WithHeaders(sreq.Headers{
+				"X-Timestamp": "1565000000",
+			}),
+			sreq.WithJSON(sreq.JSON{
+				"msg": "hello world",
+			}, false),
+			sreq.WithHMAC(&sreq.HMACSigner{
+				Key:           key,
+				SignedHeaders: []string{"Host", "X-Timestamp"},
+			}),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithHMAC_Default(t *testing.T) {
+	key := []byte("secret")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, key)
+		mac.Write(b)
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	// The default signer signs the payload set by the per-call options.
+	req := sreq.New(nil)
+	req.SetDefaultRequestOpts(
+		sreq.WithHMAC(&sreq.HMACSigner{
+			Key: key,
+			Canonicalize: func(hr *http.Request, body []byte) string {
+				return string(body)
+			},
+		}),
+	)
+	_, err := req.
+		Post(ts.URL,
+			sreq.WithText("hello world"),
+		).
+		EnsureStatusOk().
+		Resolve()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithHMAC_Custom(t *testing.T) {
+	httpReq, err := sreq.NewRequest(sreq.MethodPost, "http://example.com/api", sreq.WithHMAC(&sreq.HMACSigner{
+		Key:  []byte("secret"),
+		Hash: sha1.New,
+		Canonicalize: func(hr *http.Request, body []byte) string {
+			return hr.Method + " " + string(body)
+		},
+		Header: "Authorization",
+		Format: func(sum []byte) string {
+			return "HMAC-SHA1 " + base64.StdEncoding.EncodeToString(sum)
+		},
+	}), sreq.WithText("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write([]byte("POST hello world"))
+	want := "HMAC-SHA1 " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if got := httpReq.Header.Get("Authorization"); got != want {
+		t.Errorf("WithHMAC got: %s, want: %s", got, want)
+	}
+
+	b, _ := ioutil.ReadAll(httpReq.Body)
+	if string(b) != "hello world" {
+		t.Errorf("WithHMAC consumed the payload, got: %s", b)
+	}
+
+	_, err = sreq.WithHMAC(&sreq.HMACSigner{})(httpReq)
+	if err == nil {
+		t.Error("WithHMAC key unchecked")
+	}
+}
@@ -30,6 +30,15 @@ type (
 
 	// Files is the same as map[string]string, used for multipart-data.
 	Files map[string]string
+
+	// MultiParams is the same as map[string][]string, used for query params with multiple values.
+	MultiParams map[string][]string
+
+	// MultiHeaders is the same as map[string][]string, used for request headers with multiple values.
+	MultiHeaders map[string][]string
+
+	// MultiForm is the same as map[string][]string, used for form-data with multiple values.
+	MultiForm map[string][]string
 )
 
 // Get returns the value from a map by the given key.
@@ -142,6 +151,91 @@ func (f Files) String() string {
 	return toJSON(f)
 }
 
+// Get returns the first value from a map by the given key.
+func (p MultiParams) Get(key string) string {
+	return firstValue(p[key])
+}
+
+// Set sets a kv pair into a map, it replaces any existing values.
+func (p MultiParams) Set(key string, value string) {
+	p[key] = []string{value}
+}
+
+// Add appends a value to the values related to the given key.
+func (p MultiParams) Add(key string, value string) {
+	p[key] = append(p[key], value)
+}
+
+// Del deletes the values related to the given key from a map.
+func (p MultiParams) Del(key string) {
+	delete(p, key)
+}
+
+// Encode encodes p into URL-escaped form sorted by key, the values of a key keep their order.
+func (p MultiParams) Encode() string {
+	return urlEncodeValues(p, true)
+}
+
+// String encodes p into URL-unescaped form sorted by key, the values of a key keep their order.
+func (p MultiParams) String() string {
+	return urlEncodeValues(p, false)
+}
+
+// Get returns the first value from a map by the given key.
+func (h MultiHeaders) Get(key string) string {
+	return firstValue(h[key])
+}
+
+// Set sets a kv pair into a map, it replaces any existing values.
+func (h MultiHeaders) Set(key string, value string) {
+	h[key] = []string{value}
+}
+
+// Add appends a value to the values related to the given key.
+func (h MultiHeaders) Add(key string, value string) {
+	h[key] = append(h[key], value)
+}
+
+// Del deletes the values related to the given key from a map.
+func (h MultiHeaders) Del(key string) {
+	delete(h, key)
+}
+
+// String returns the JSON-encoded text representation of h.
+func (h MultiHeaders) String() string {
+	return toJSON(h)
+}
+
+// Get returns the first value from a map by the given key.
+func (f MultiForm) Get(key string) string {
+	return firstValue(f[key])
+}
+
+// Set sets a kv pair into a map, it replaces any existing values.
+func (f MultiForm) Set(key string, value string) {
+	f[key] = []string{value}
+}
+
+// Add appends a value to the values related to the given key.
+func (f MultiForm) Add(key string, value string) {
+	f[key] = append(f[key], value)
+}
+
+// Del deletes the values related to the given key from a map.
+func (f MultiForm) Del(key string) {
+	delete(f, key)
+}
+
+// Encode encodes f into URL-escaped form sorted by key, the values of a key keep their order.
+func (f MultiForm) Encode() string {
+	return urlEncodeValues(f, true)
+}
+
+// String encodes f into URL-unescaped form sorted by key, the values of a key keep their order.
+func (f MultiForm) String() string {
+	return urlEncodeValues(f, false)
+}
+
 // ExistsFile checks whether a file exists or not.
 func ExistsFile(filename string) (bool, error) {
 	fi, err := os.Stat(filename)
@@ -190,6 +284,46 @@ func urlEncode(v map[string]string, escape bool) string {
 	return sb.String()
 }
 
+func urlEncodeValues(v map[string][]string, escape bool) string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		for _, value := range v[k] {
+			if sb.Len() > 0 {
+				sb.WriteString("&")
+			}
+
+			if escape {
+				sb.WriteString(stdurl.QueryEscape(k))
+			} else {
+				sb.WriteString(k)
+			}
+
+			sb.WriteString("=")
+
+			if escape {
+				sb.WriteString(stdurl.QueryEscape(value))
+			} else {
+				sb.WriteString(value)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
 func toJSON(data interface{}) string {
 	b, err := Marshal(data, "", "\t", false)
 	if err != nil {
@@ -103,6 +103,69 @@ func TestForm(t *testing.T) {
 	}
 }
 
+func TestMultiParams(t *testing.T) {
+	p := make(sreq.MultiParams)
+	p.Set("id", "2")
+	p.Add("id", "1")
+	p.Add("id", "3")
+	p.Add("q", "sreq")
+	if got := p["id"]; !reflect.DeepEqual(got, []string{"2", "1", "3"}) {
+		t.Errorf("MultiParams_Add got: %v", got)
+	}
+	if got := p.Get("id"); got != "2" {
+		t.Errorf("MultiParams_Get got: %s, want: %s", got, "2")
+	}
+
+	want := "id=2&id=1&id=3&q=sreq"
+	if got := p.Encode(); got != want {
+		t.Errorf("MultiParams_Encode got: %s, want: %s", got, want)
+	}
+
+	p.Set("id", "4")
+	p.Del("q")
+	if got := p.String(); got != "id=4" {
+		t.Errorf("MultiParams_String got: %s, want: %s", got, "id=4")
+	}
+}
+
+func TestMultiHeaders(t *testing.T) {
+	h1 := make(sreq.MultiHeaders)
+	h1.Add("Accept", "text/html")
+	h1.Add("Accept", "application/json")
+	if got := h1["Accept"]; !reflect.DeepEqual(got, []string{"text/html", "application/json"}) {
+		t.Errorf("MultiHeaders_Add got: %v", got)
+	}
+
+	h2 := make(sreq.MultiHeaders)
+	if err := json.Unmarshal([]byte(h1.String()), &h2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h2, h1) {
+		t.Error("MultiHeaders_String test failed")
+	}
+
+	h1.Del("Accept")
+	if h1.Get("Accept") != "" || len(h1) != 0 {
+		t.Error("MultiHeaders_Del test failed")
+	}
+}
+
+func TestMultiForm(t *testing.T) {
+	f := sreq.MultiForm{
+		"q": {"Go语言"},
+	}
+	f.Add("q", "sreq")
+	f.Set("limit", "100")
+	want := "limit=100&q=Go语言&q=sreq"
+	if got := f.String(); got != want {
+		t.Errorf("MultiForm_String got: %s, want: %s", got, want)
+	}
+	want = "limit=100&q=Go%E8%AF%AD%E8%A8%80&q=sreq"
+	if got := f.Encode(); got != want {
+		t.Errorf("MultiForm_Encode got: %s, want: %s", got, want)
+	}
+}
+
 func TestJSON(t *testing.T) {
 	j := make(sreq.JSON)
 
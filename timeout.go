@@ -0,0 +1,106 @@
+package sreq
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// WithTimeout sets a timeout of the HTTP request, including reading the response body.
+// Unlike WithContext, the derived context is released automatically once the response body
+// is fully read or closed, e.g. by Raw, Text, JSON or Save.
+// It takes precedence over the timeout of the HTTP client, i.e. http.Client.Timeout
+// doesn't apply to the HTTP request. The timeout starts when the request is sent.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(hr *http.Request) (*http.Request, error) {
+		return withSettings(hr, func(s *requestSettings) {
+			if s.timeout <= 0 || timeout < s.timeout {
+				s.timeout = timeout
+			}
+		}), nil
+	}
+}
+
+// applyTimeout derives the context of the HTTP request with the timeout set by WithTimeout.
+func applyTimeout(httpReq *http.Request) *http.Request {
+	s := settingsOf(httpReq)
+	if s.timeout <= 0 || s.timeoutCancel != nil {
+		return httpReq
+	}
+
+	ctx, cancel := context.WithTimeout(httpReq.Context(), s.timeout)
+	return withSettings(httpReq.WithContext(ctx), func(s *requestSettings) {
+		s.timeoutCancel = cancel
+	})
+}
+
+// releaseTimeout releases the context derived by WithTimeout when the response is done.
+func releaseTimeout(httpReq *http.Request, resp *Response) {
+	cancel := settingsOf(httpReq).timeoutCancel
+	if cancel == nil {
+		return
+	}
+
+	if resp.Err != nil || resp.R == nil || resp.R.Body == nil {
+		cancel()
+		return
+	}
+	resp.R.Body = newHookBody(resp.R.Body, cancel)
+}
+
+// clientFor returns hc, or a copy of hc without its timeout if the HTTP request
+// has a timeout set by WithTimeout.
+func clientFor(hc *http.Client, httpReq *http.Request) *http.Client {
+	if hc.Timeout <= 0 || settingsOf(httpReq).timeout <= 0 {
+		return hc
+	}
+
+	c := *hc
+	c.Timeout = 0
+	return &c
+}
+
+// SetConnectTimeout sets the timeout for establishing TCP connections.
+func SetConnectTimeout(timeout time.Duration) error {
+	return std.SetConnectTimeout(timeout)
+}
+
+// SetConnectTimeout sets the timeout for establishing TCP connections.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) SetConnectTimeout(timeout time.Duration) error {
+	return c.configureTransport(func(t *http.Transport) {
+		t.DialContext = (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	})
+}
+
+// SetTLSHandshakeTimeout sets the timeout for TLS handshakes.
+func SetTLSHandshakeTimeout(timeout time.Duration) error {
+	return std.SetTLSHandshakeTimeout(timeout)
+}
+
+// SetTLSHandshakeTimeout sets the timeout for TLS handshakes.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) SetTLSHandshakeTimeout(timeout time.Duration) error {
+	return c.configureTransport(func(t *http.Transport) {
+		t.TLSHandshakeTimeout = timeout
+	})
+}
+
+// SetResponseHeaderTimeout sets the timeout for waiting for the response headers
+// after fully writing the request.
+func SetResponseHeaderTimeout(timeout time.Duration) error {
+	return std.SetResponseHeaderTimeout(timeout)
+}
+
+// SetResponseHeaderTimeout sets the timeout for waiting for the response headers
+// after fully writing the request.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) SetResponseHeaderTimeout(timeout time.Duration) error {
+	return c.configureTransport(func(t *http.Transport) {
+		t.ResponseHeaderTimeout = timeout
+	})
+}
@@ -0,0 +1,138 @@
+package sreq_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/delay" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, "hello world")
+	}))
+	defer ts.Close()
+
+	_, err := sreq.
+		Get(ts.URL+"/delay",
+			sreq.WithTimeout(50*time.Millisecond),
+		).
+		Resolve()
+	if err == nil {
+		t.Error("WithTimeout test failed")
+	}
+
+	resp := sreq.
+		Get(ts.URL,
+			sreq.WithTimeout(time.Second),
+		).
+		EnsureStatusOk()
+	if resp.Err != nil {
+		t.Fatal(resp.Err)
+	}
+	if err = resp.R.Request.Context().Err(); err != nil {
+		t.Errorf("Context released before reading the body: %v", err)
+	}
+
+	data, err := resp.Text()
+	if err != nil || data != "hello world" {
+		t.Errorf("Response_Text got: %q, %v", data, err)
+	}
+	if resp.R.Request.Context().Err() == nil {
+		t.Error("Context not released after reading the body")
+	}
+}
+
+func TestWithTimeout_WithContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/delay" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, "hello world")
+	}))
+	defer ts.Close()
+
+	withTimeout, withContext := sreq.WithTimeout(50*time.Millisecond), sreq.WithContext(context.Background())
+	for _, opts := range [][]sreq.RequestOption{
+		{withTimeout, withContext},
+		{withContext, withTimeout},
+	} {
+		if _, err := sreq.New(nil).Get(ts.URL+"/delay", opts...).Resolve(); err == nil {
+			t.Error("WithTimeout with WithContext test failed")
+		}
+
+		resp := sreq.New(nil).Get(ts.URL, opts...).EnsureStatusOk()
+		if _, err := resp.Raw(); err != nil {
+			t.Fatal(err)
+		}
+		if resp.R.Request.Context().Err() == nil {
+			t.Error("Context not released after reading the body")
+		}
+	}
+}
+
+func TestWithTimeout_ClientTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "hello world")
+	}))
+	defer ts.Close()
+
+	req := sreq.New(&http.Client{
+		Timeout: 50 * time.Millisecond,
+	})
+	if _, err := req.Get(ts.URL).Resolve(); err == nil {
+		t.Error("Client timeout test failed")
+	}
+
+	data, err := req.
+		Get(ts.URL,
+			sreq.WithTimeout(time.Second),
+		).
+		EnsureStatusOk().
+		Text()
+	if err != nil || data != "hello world" {
+		t.Errorf("WithTimeout beyond the client timeout got: %q, %v", data, err)
+	}
+}
+
+func TestClient_SetResponseHeaderTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	if err := req.SetConnectTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.SetTLSHandshakeTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.SetResponseHeaderTimeout(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := req.Get(ts.URL).Resolve(); err == nil {
+		t.Error("Client_SetResponseHeaderTimeout test failed")
+	}
+
+	req = sreq.New(&http.Client{
+		Transport: roundTripperFunc(nil),
+	})
+	if err := req.SetResponseHeaderTimeout(time.Second); err == nil {
+		t.Error("Non *http.Transport unchecked")
+	}
+}
@@ -0,0 +1,167 @@
+package sreq
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// SetClientCertificate loads a certificate/key pair from PEM files for mutual TLS.
+func SetClientCertificate(certFile string, keyFile string) error {
+	return std.SetClientCertificate(certFile, keyFile)
+}
+
+// SetClientCertificate loads a certificate/key pair from PEM files for mutual TLS,
+// it's presented to the servers which request client certificates.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) SetClientCertificate(certFile string, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	return c.configureTLS(func(config *tls.Config) {
+		config.Certificates = append(config.Certificates, cert)
+	})
+}
+
+// AppendRootCAs appends the certificates in the PEM files to the trusted root CAs.
+func AppendRootCAs(pemFiles ...string) error {
+	return std.AppendRootCAs(pemFiles...)
+}
+
+// AppendRootCAs appends the certificates in the PEM files to the trusted root CAs,
+// which are the system ones unless they have been replaced.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) AppendRootCAs(pemFiles ...string) error {
+	pems := make([][]byte, 0, len(pemFiles))
+	for _, pemFile := range pemFiles {
+		b, err := ioutil.ReadFile(pemFile)
+		if err != nil {
+			return err
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(b) {
+			return fmt.Errorf("sreq: no certificates found in %s", pemFile)
+		}
+		pems = append(pems, b)
+	}
+
+	return c.configureTLS(func(config *tls.Config) {
+		if config.RootCAs == nil {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			config.RootCAs = pool
+		}
+		for _, b := range pems {
+			config.RootCAs.AppendCertsFromPEM(b)
+		}
+	})
+}
+
+// SetMinTLSVersion sets the minimum TLS version, like tls.VersionTLS12.
+func SetMinTLSVersion(version uint16) error {
+	return std.SetMinTLSVersion(version)
+}
+
+// SetMinTLSVersion sets the minimum TLS version, like tls.VersionTLS12.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) SetMinTLSVersion(version uint16) error {
+	return c.configureTLS(func(config *tls.Config) {
+		config.MinVersion = version
+	})
+}
+
+// PinCertificates pins the certificates of the servers by SPKI SHA-256 hashes.
+func PinCertificates(pins ...string) error {
+	return std.PinCertificates(pins...)
+}
+
+// PinCertificates pins the certificates of the servers by the base64-encoded SHA-256 hashes of
+// their Subject Public Key Info, optionally prefixed with "sha256/", which can be obtained by:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der |
+//	    openssl dgst -sha256 -binary | base64
+//
+// The connections are refused unless one of the certificates in the verified chains matches a pin,
+// so a CA or an intermediate certificate can be pinned too. If the verification is skipped by
+// SetInsecureSkipVerify, only the leaf certificate is checked. Calling it with no pins removes pinning.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) PinCertificates(pins ...string) error {
+	hashes := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		pin = strings.TrimPrefix(pin, "sha256/")
+		b, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("sreq: invalid SPKI SHA-256 pin: %q", pin)
+		}
+		hashes[string(b)] = true
+	}
+
+	return c.configureTLS(func(config *tls.Config) {
+		if len(hashes) == 0 {
+			config.VerifyPeerCertificate = nil
+			return
+		}
+
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			chains := verifiedChains
+			if len(chains) == 0 {
+				// Verification is skipped, only the leaf certificate can be trusted since the handshake
+				// proves the server holds its key, the others can be appended by anyone.
+				if len(rawCerts) == 0 {
+					return errors.New("sreq: no certificate presented by the server")
+				}
+				cert, err := x509.ParseCertificate(rawCerts[0])
+				if err != nil {
+					return err
+				}
+				chains = [][]*x509.Certificate{{cert}}
+			}
+
+			for _, chain := range chains {
+				for _, cert := range chain {
+					sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					if hashes[string(sum[:])] {
+						return nil
+					}
+				}
+			}
+			return errors.New("sreq: no certificate matches the pins")
+		}
+	})
+}
+
+// SetInsecureSkipVerify makes c accept any certificate presented by the servers, if skip is true.
+// It's for the local development only and must not be used in production.
+func SetInsecureSkipVerify(skip bool) error {
+	return std.SetInsecureSkipVerify(skip)
+}
+
+// SetInsecureSkipVerify makes c accept any certificate presented by the servers, if skip is true.
+// It's for the local development only and must not be used in production.
+// It requires the transport of c to be an *http.Transport, and should be called before sending requests.
+func (c *Client) SetInsecureSkipVerify(skip bool) error {
+	return c.configureTLS(func(config *tls.Config) {
+		config.InsecureSkipVerify = skip
+	})
+}
+
+// configureTLS applies fn to a copy of the TLS config of the transport of c.
+func (c *Client) configureTLS(fn func(config *tls.Config)) error {
+	return c.configureTransport(func(t *http.Transport) {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = new(tls.Config)
+		} else {
+			t.TLSClientConfig = t.TLSClientConfig.Clone()
+		}
+		fn(t.TLSClientConfig)
+	})
+}
@@ -0,0 +1,286 @@
+package sreq_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/winterssy/sreq"
+)
+
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert issues a certificate signed by parent, or a self-signed CA if parent is nil,
+// and writes the PEM files into dir.
+func newTestCert(t *testing.T, dir string, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	err = ioutil.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err == nil {
+		err = ioutil.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+func (tc *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{tc.cert.Raw},
+		PrivateKey:  tc.key,
+	}
+}
+
+func (tc *testCert) pin() string {
+	sum := sha256.Sum256(tc.cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func newTLSServer(server *testCert, clientCA *testCert) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate()},
+		MaxVersion:   tls.VersionTLS12,
+	}
+	if clientCA != nil {
+		pool := x509.NewCertPool()
+		pool.AddCert(clientCA.cert)
+		ts.TLS.ClientCAs = pool
+		ts.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	ts.StartTLS()
+	return ts
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, dir, "ca", nil)
+	server := newTestCert(t, dir, "server", ca)
+	client := newTestCert(t, dir, "client", ca)
+
+	ts := newTLSServer(server, nil)
+	defer ts.Close()
+	mtls := newTLSServer(server, ca)
+	defer mtls.Close()
+
+	get := func(req *sreq.Client, url string) error {
+		_, err := req.Get(url).EnsureStatusOk().Resolve()
+		return err
+	}
+
+	req := sreq.New(nil)
+	if err = get(req, ts.URL); err == nil {
+		t.Error("Unknown authority unchecked")
+	}
+	if err = req.AppendRootCAs(client.keyFile); err == nil {
+		t.Error("AppendRootCAs PEM without certificates unchecked")
+	}
+	if err = req.AppendRootCAs(ca.certFile); err != nil {
+		t.Fatal(err)
+	}
+	if err = get(req, ts.URL); err != nil {
+		t.Error(err)
+	}
+
+	if err = get(req, mtls.URL); err == nil {
+		t.Error("Missing client certificate unchecked")
+	}
+	if err = req.SetClientCertificate(client.certFile, client.keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if err = get(req, mtls.URL); err != nil {
+		t.Error(err)
+	}
+
+	if err = req.SetMinTLSVersion(tls.VersionTLS13); err != nil {
+		t.Fatal(err)
+	}
+	if err = get(req, ts.URL); err == nil {
+		t.Error("SetMinTLSVersion unchecked")
+	}
+}
+
+func TestPinCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, dir, "ca", nil)
+	server := newTestCert(t, dir, "server", ca)
+	other := newTestCert(t, dir, "other", nil)
+	ts := newTLSServer(server, nil)
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	if err = req.AppendRootCAs(ca.certFile); err != nil {
+		t.Fatal(err)
+	}
+	if err = req.PinCertificates("invalid"); err == nil {
+		t.Error("PinCertificates invalid pin unchecked")
+	}
+
+	tests := []struct {
+		pins []string
+		ok   bool
+	}{
+		{[]string{server.pin()}, true},
+		{[]string{"sha256/" + ca.pin()}, true},
+		{[]string{other.pin()}, false},
+		{nil, true},
+	}
+	for _, test := range tests {
+		if err = req.PinCertificates(test.pins...); err != nil {
+			t.Fatal(err)
+		}
+		_, err = req.Get(ts.URL).EnsureStatusOk().Resolve()
+		if (err == nil) != test.ok {
+			t.Errorf("PinCertificates %v got error: %v", test.pins, err)
+		}
+	}
+}
+
+func TestSetInsecureSkipVerify(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	if err := req.SetInsecureSkipVerify(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := req.Get(ts.URL).EnsureStatusOk().Resolve(); err != nil {
+		t.Error(err)
+	}
+
+	// Pinning still works without verification.
+	if err := req.PinCertificates(base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := req.Get(ts.URL).EnsureStatusOk().Resolve(); err == nil {
+		t.Error("PinCertificates without verification unchecked")
+	}
+}
+
+func TestPinCertificates_InsecureSkipVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sreq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, dir, "ca", nil)
+	server := newTestCert(t, dir, "server", ca)
+	pinned := newTestCert(t, dir, "pinned", nil)
+
+	// The pinned certificate is public, it's appended to the chain of a different leaf.
+	cert := server.tlsCertificate()
+	cert.Certificate = append(cert.Certificate, pinned.cert.Raw)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	req := sreq.New(nil)
+	if err = req.SetInsecureSkipVerify(true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pin string
+		ok  bool
+	}{
+		{pinned.pin(), false},
+		{ca.pin(), false},
+		{server.pin(), true},
+	}
+	for _, test := range tests {
+		if err = req.PinCertificates(test.pin); err != nil {
+			t.Fatal(err)
+		}
+		_, err = req.Get(ts.URL).EnsureStatusOk().Resolve()
+		if (err == nil) != test.ok {
+			t.Errorf("PinCertificates without verification %s got error: %v", test.pin, err)
+		}
+	}
+}